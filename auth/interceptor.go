@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Rule describes who may call a single gRPC method. A caller is let through
+// when the method is public, when their role is listed in Roles, or when
+// Owner returns their user id for the request. A rule with neither Roles nor
+// Owner admits any authenticated caller. API keys are only admitted by
+// Scopes, never by Roles or Owner.
+type Rule struct {
+	Public bool
+	Roles  []string
+	Owner  func(req interface{}) string
+	Scopes []string
+}
+
+// KeyValidator resolves an API key to the claims it grants, or fails when the
+// key is unknown, revoked or expired.
+type KeyValidator func(ctx context.Context, key string) (*Claims, error)
+
+// Policy maps full gRPC method names to rules. Methods without a rule are denied.
+type Policy map[string]Rule
+
+type claimsKey struct{}
+
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if c.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Claims) HasScope(scopes ...string) bool {
+	for _, have := range c.Scopes {
+		for _, want := range scopes {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor authenticates callers by bearer token, or by the
+// x-api-key metadata when keys is not nil, and applies policy to them.
+func UnaryServerInterceptor(secret []byte, keys KeyValidator, policy Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rule, ok := policy[info.FullMethod]
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "No access policy for %s", info.FullMethod)
+		}
+		if rule.Public {
+			// Credentials are optional here, but handlers still get to know
+			// who is calling when they are valid.
+			if claims, err := authenticate(ctx, secret, keys); err == nil {
+				ctx = context.WithValue(ctx, claimsKey{}, claims)
+			}
+			return handler(ctx, req)
+		}
+
+		claims, err := authenticate(ctx, secret, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		if !rule.allows(claims, req) {
+			return nil, status.Errorf(codes.PermissionDenied, "Not allowed to call %s", info.FullMethod)
+		}
+
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+func authenticate(ctx context.Context, secret []byte, keys KeyValidator) (*Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		if keys == nil {
+			return nil, status.Errorf(codes.Unauthenticated, "API keys are not accepted here")
+		}
+		claims, err := keys(ctx, values[0])
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "Invalid API key: %v", err)
+		}
+		return claims, nil
+	}
+
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := ParseAccessToken(secret, token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid access token: %v", err)
+	}
+	return claims, nil
+}
+
+func (r Rule) allows(claims *Claims, req interface{}) bool {
+	if claims.APIKeyID != "" {
+		return claims.HasScope(r.Scopes...)
+	}
+	if len(r.Roles) == 0 && r.Owner == nil {
+		return true
+	}
+	if claims.HasRole(r.Roles...) {
+		return true
+	}
+	return r.Owner != nil && r.Owner(req) == claims.Subject
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", status.Errorf(codes.Unauthenticated, "Missing authorization metadata")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return "", status.Errorf(codes.Unauthenticated, "Authorization must be a bearer token")
+	}
+	return token, nil
+}
+
+// OutgoingContext carries the credentials and client address of an HTTP
+// request over to the gRPC call made on its behalf.
+func OutgoingContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if header := r.Header.Get("Authorization"); header != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", header)
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", key)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-forwarded-for", host)
+	}
+	return ctx
+}
+
+// ForwardContext passes the caller's credentials on to a downstream gRPC
+// call, so the downstream service applies its own policy to the same caller.
+func ForwardContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", values[0])
+	}
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", values[0])
+	}
+	return ctx
+}
+
+// ServiceContext authenticates a downstream gRPC call as the calling service
+// instead of the caller it acts for, for calls the caller is not allowed to
+// make itself. Policies admit it through RoleService.
+func ServiceContext(ctx context.Context, service string) (context.Context, error) {
+	secret, err := Secret()
+	if err != nil {
+		return nil, err
+	}
+	token, err := IssueServiceToken(secret, service, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), nil
+}
+
+// ClientIP returns the address of the caller. The forwarded address is only
+// trusted when the call comes from loopback, i.e. from our own HTTP handlers.
+func ClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if forwarded := md.Get("x-forwarded-for"); len(forwarded) > 0 && forwarded[0] != "" {
+			return forwarded[0]
+		}
+	}
+	return host
+}
@@ -0,0 +1,62 @@
+// Package etag exposes document versions over HTTP as entity tags, so
+// clients can make conditional updates with If-Match.
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrMissing = errors.New("If-Match header with the current ETag is required")
+	ErrInvalid = errors.New("If-Match header is not an ETag issued by this API")
+)
+
+// Format returns the ETag for a version.
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// IfMatch returns the version named by the request's If-Match header.
+func IfMatch(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, ErrMissing
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalid
+	}
+	return version, nil
+}
+
+// ErrorStatus picks the HTTP status for a failed conditional update.
+func ErrorStatus(err error) int {
+	switch status.Code(err) {
+	case codes.Aborted:
+		return http.StatusPreconditionFailed
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionRequired
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
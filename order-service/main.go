@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"goFinalProject/auth"
 	pb "goFinalProject/proto/proto"
 
 	"github.com/gorilla/mux"
@@ -16,26 +17,34 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var orderCollection *mongo.Collection
 var userClient pb.UserServiceClient
 var productClient pb.ProductServiceClient
 
+// requireVerifiedEmail rejects orders from users who have not verified their email.
+var requireVerifiedEmail bool
+
 type ProductItemInput struct {
-	ProductId string `json:"productId"`
-	Quantity  int32  `json:"quantity"`
+	ProductId  string `json:"productId"`
+	VariantSku string `json:"variantSku"`
+	Quantity   int32  `json:"quantity"`
 }
 
 type CreateOrderInput struct {
-	UserId   string             `json:"userId"`
-	Products []ProductItemInput `json:"products"`
+	UserId            string             `json:"userId"`
+	Products          []ProductItemInput `json:"products"`
+	ShippingAddressId string             `json:"shippingAddressId"`
 }
 
 func init() {
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
+	requireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
 }
 
 func InitMongo() {
@@ -66,6 +75,22 @@ func initGRPCClients() {
 	productClient = pb.NewProductServiceClient(productConn)
 }
 
+// validateAPIKey checks keys presented to this service against user-service,
+// which owns them.
+func validateAPIKey(ctx context.Context, key string) (*auth.Claims, error) {
+	principal, err := userClient.AuthenticateAPIKey(ctx, &pb.AuthenticateAPIKeyRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		APIKeyID: principal.KeyId,
+		Scopes:   principal.Scopes,
+	}
+	claims.Subject = principal.UserId
+	return claims, nil
+}
+
 func grpcDial() *grpc.ClientConn {
 	conn, err := grpc.Dial("localhost:50053", grpc.WithInsecure())
 	if err != nil {
@@ -83,38 +108,26 @@ func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("req.UserId is:", input.UserId)
 
-	_, err := userClient.GetUser(context.Background(), &pb.GetUserRequest{Id: input.UserId})
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
-
+	// CreateOrder prices the items and reserves their stock itself.
 	var productItems []*pb.ProductItem
-	var totalPrice float64
-
 	for _, item := range input.Products {
-		productResp, err := productClient.GetProduct(context.Background(), &pb.GetProductRequest{Id: item.ProductId})
-		if err != nil {
-			http.Error(w, "Product not found: "+item.ProductId, http.StatusNotFound)
-			return
-		}
 		productItems = append(productItems, &pb.ProductItem{
-			ProductId: item.ProductId,
-			Quantity:  item.Quantity,
+			ProductId:  item.ProductId,
+			Quantity:   item.Quantity,
+			VariantSku: item.VariantSku,
 		})
-		totalPrice += productResp.Price * float64(item.Quantity)
 	}
 
 	req := &pb.CreateOrderRequest{
-		UserId:     input.UserId,
-		Products:   productItems,
-		TotalPrice: totalPrice,
+		UserId:            input.UserId,
+		Products:          productItems,
+		ShippingAddressId: input.ShippingAddressId,
 	}
 
 	grpcClient := pb.NewOrderServiceClient(grpcDial())
-	resp, err := grpcClient.CreateOrder(context.Background(), req)
+	resp, err := grpcClient.CreateOrder(auth.OutgoingContext(r), req)
 	if err != nil {
-		http.Error(w, "Error creating order: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Error creating order: "+err.Error(), httpStatus(err))
 		return
 	}
 
@@ -122,10 +135,26 @@ func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// httpStatus maps an error from CreateOrder to the status of the response.
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.FailedPrecondition:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func GetOrderHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	grpcClient := pb.NewOrderServiceClient(grpcDial())
-	resp, err := grpcClient.GetOrder(context.Background(), &pb.GetOrderRequest{Id: id})
+	resp, err := grpcClient.GetOrder(auth.OutgoingContext(r), &pb.GetOrderRequest{Id: id})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -136,7 +165,7 @@ func GetOrderHandler(w http.ResponseWriter, r *http.Request) {
 func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	grpcClient := pb.NewOrderServiceClient(grpcDial())
 
-	resp, err := grpcClient.GetOrders(context.Background(), &pb.GetOrdersRequest{})
+	resp, err := grpcClient.GetOrders(auth.OutgoingContext(r), &pb.GetOrdersRequest{})
 	if err != nil {
 		http.Error(w, "Failed to fetch orders: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -146,16 +175,41 @@ func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+var authPolicy = auth.Policy{
+	pb.OrderService_CreateOrder_FullMethodName: {
+		Roles:  []string{auth.RoleAdmin},
+		Owner:  func(req interface{}) string { return req.(*pb.CreateOrderRequest).UserId },
+		Scopes: []string{auth.ScopeOrdersWrite},
+	},
+	// GetOrder checks ownership itself once the order has been loaded.
+	pb.OrderService_GetOrder_FullMethodName:    {Scopes: []string{auth.ScopeOrdersRead}},
+	pb.OrderService_GetOrders_FullMethodName:   {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeOrdersRead}},
+	pb.OrderService_DeleteOrder_FullMethodName: {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeOrdersWrite}},
+	pb.OrderService_GetUserOrders_FullMethodName: {
+		Roles:  []string{auth.RoleAdmin},
+		Owner:  func(req interface{}) string { return req.(*pb.GetUserOrdersRequest).UserId },
+		Scopes: []string{auth.ScopeOrdersRead},
+	},
+	// Users erase themselves through user-service's EraseUser, which calls
+	// this as a service; they cannot anonymize their orders on their own.
+	pb.OrderService_AnonymizeUserOrders_FullMethodName: {Roles: []string{auth.RoleAdmin, auth.RoleService}},
+}
+
 func main() {
 	InitMongo()
 	initGRPCClients()
 
+	secret, err := auth.Secret()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	lis, err := net.Listen("tcp", ":50053")
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryServerInterceptor(secret, validateAPIKey, authPolicy)))
 	pb.RegisterOrderServiceServer(grpcServer, &OrderServiceServer{})
 
 	go func() {
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"log"
+	"time"
 
+	"goFinalProject/auth"
 	pb "goFinalProject/proto/proto"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type OrderServiceServer struct {
@@ -14,43 +19,185 @@ type OrderServiceServer struct {
 }
 
 func (s *OrderServiceServer) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.OrderResponse, error) {
+	items, totalPrice, err := priceItems(ctx, req.Products)
+	if err != nil {
+		return nil, err
+	}
+
+	// The caller may only be allowed to create orders, so the user, address
+	// and stock are handled with order-service's own credentials.
+	serviceCtx, err := auth.ServiceContext(ctx, "order-service")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to authenticate as order-service: %v", err)
+	}
+
+	user, err := userClient.GetUser(serviceCtx, &pb.GetUserRequest{Id: req.UserId})
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "User not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch user: %v", err)
+	}
+	if requireVerifiedEmail && !user.Verified {
+		return nil, status.Errorf(codes.PermissionDenied, "Email address is not verified")
+	}
+
+	var shippingAddress *pb.Address
+	if req.ShippingAddressId != "" {
+		shippingAddress, err = userClient.GetAddress(serviceCtx, &pb.GetAddressRequest{UserId: req.UserId, AddressId: req.ShippingAddressId})
+		if status.Code(err) == codes.NotFound {
+			return nil, status.Errorf(codes.NotFound, "Shipping address not found")
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to fetch shipping address: %v", err)
+		}
+	}
+
+	// Stock for every line is held before the order exists and committed once
+	// it is stored; it is released again if the order cannot be stored.
+	lines := stockLines(items)
+	reservation, err := productClient.ReserveStock(serviceCtx, &pb.ReserveStockRequest{Lines: lines, UserId: req.UserId})
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, err
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to reserve stock: %v", err)
+	}
+
 	var productDocs []bson.M
-	for _, item := range req.Products {
-		productDocs = append(productDocs, bson.M{
+	for _, item := range items {
+		productDoc := bson.M{
 			"product_id": item.ProductId,
 			"quantity":   item.Quantity,
-		})
+			"unit_price": item.UnitPrice,
+		}
+		if item.VariantSku != "" {
+			productDoc["variant_sku"] = item.VariantSku
+		}
+		productDocs = append(productDocs, productDoc)
 	}
 
 	order := bson.M{
-		"user_id":     req.UserId,
-		"products":    productDocs,
-		"total_price": req.TotalPrice,
+		"user_id":        req.UserId,
+		"products":       productDocs,
+		"total_price":    totalPrice,
+		"reservation_id": reservation.Id,
+	}
+	if shippingAddress != nil {
+		order["shipping_address"] = addressDoc(shippingAddress)
 	}
 
 	res, err := orderCollection.InsertOne(ctx, order)
 	if err != nil {
-		return nil, err
+		releaseReservation(serviceCtx, reservation.Id, req.UserId)
+		return nil, status.Errorf(codes.Internal, "Failed to store order: %v", err)
 	}
 
 	oid := res.InsertedID.(primitive.ObjectID)
 
-	var grpcProducts []*pb.ProductItem
-	for _, item := range req.Products {
-		grpcProducts = append(grpcProducts, &pb.ProductItem{
-			ProductId: item.ProductId,
-			Quantity:  item.Quantity,
-		})
+	// An order whose stock cannot be committed, e.g. because the reservation
+	// expired, is taken back out.
+	_, err = productClient.CommitReservation(serviceCtx, &pb.CommitReservationRequest{
+		Id:      reservation.Id,
+		OrderId: oid.Hex(),
+		UserId:  req.UserId,
+		Lines:   lines,
+	})
+	if err != nil {
+		if _, deleteErr := orderCollection.DeleteOne(ctx, bson.M{"_id": oid}); deleteErr != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to remove order %s after its reservation failed: %v", oid.Hex(), deleteErr)
+		}
+		releaseReservation(serviceCtx, reservation.Id, req.UserId)
+		return nil, status.Errorf(codes.FailedPrecondition, "Failed to commit stock reservation: %v", err)
 	}
 
 	return &pb.OrderResponse{
-		Id:         oid.Hex(),
-		UserId:     req.UserId,
-		Products:   grpcProducts,
-		TotalPrice: req.TotalPrice,
+		Id:              oid.Hex(),
+		UserId:          req.UserId,
+		Products:        items,
+		TotalPrice:      totalPrice,
+		ShippingAddress: shippingAddress,
 	}, nil
 }
 
+func stockLines(items []*pb.ProductItem) []*pb.StockLine {
+	var lines []*pb.StockLine
+	for _, item := range items {
+		lines = append(lines, &pb.StockLine{
+			ProductId:  item.ProductId,
+			VariantSku: item.VariantSku,
+			Quantity:   item.Quantity,
+		})
+	}
+	return lines
+}
+
+// releaseReservation puts the stock of an order that was not placed back.
+// A reservation that cannot be released expires on its own, so failures are
+// only logged.
+func releaseReservation(ctx context.Context, id, userID string) {
+	if _, err := productClient.ReleaseReservation(ctx, &pb.ReleaseReservationRequest{Id: id, UserId: userID}); err != nil {
+		log.Printf("Failed to release reservation %s: %v", id, err)
+	}
+}
+
+// priceItems prices every line from the catalog and sums the total. Prices
+// sent by the client are ignored.
+func priceItems(ctx context.Context, in []*pb.ProductItem) ([]*pb.ProductItem, float64, error) {
+	if len(in) == 0 {
+		return nil, 0, status.Errorf(codes.InvalidArgument, "An order needs at least one product")
+	}
+
+	var items []*pb.ProductItem
+	var total float64
+	for _, item := range in {
+		if item.Quantity <= 0 {
+			return nil, 0, status.Errorf(codes.InvalidArgument, "Quantity must be positive")
+		}
+		product, err := productClient.GetProduct(ctx, &pb.GetProductRequest{Id: item.ProductId})
+		if status.Code(err) == codes.NotFound {
+			return nil, 0, status.Errorf(codes.NotFound, "Product not found: %s", item.ProductId)
+		}
+		if err != nil {
+			return nil, 0, status.Errorf(codes.Internal, "Failed to fetch product %s: %v", item.ProductId, err)
+		}
+
+		price, err := unitPrice(product, item.VariantSku)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, &pb.ProductItem{
+			ProductId:  item.ProductId,
+			Quantity:   item.Quantity,
+			VariantSku: item.VariantSku,
+			UnitPrice:  price,
+		})
+		total += price * float64(item.Quantity)
+	}
+	return items, total, nil
+}
+
+// unitPrice is the price of one unit of a product, or of its variant with
+// the given SKU, at its effective price so running sales apply. Products
+// with variants can only be ordered by variant.
+func unitPrice(product *pb.ProductResponse, sku string) (float64, error) {
+	if sku == "" {
+		if len(product.Variants) > 0 {
+			return 0, status.Errorf(codes.InvalidArgument, "Product %s has variants, variant_sku is required", product.Id)
+		}
+		return product.EffectivePrice, nil
+	}
+	for _, variant := range product.Variants {
+		if variant.Sku == sku {
+			if variant.Price != nil {
+				return *variant.Price, nil
+			}
+			return product.EffectivePrice, nil
+		}
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "Product %s has no variant %s", product.Id, sku)
+}
+
 func (s *OrderServiceServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.OrderResponse, error) {
 	oid, err := primitive.ObjectIDFromHex(req.Id)
 	if err != nil {
@@ -63,26 +210,56 @@ func (s *OrderServiceServer) GetOrder(ctx context.Context, req *pb.GetOrderReque
 		return nil, err
 	}
 
-	rawProducts := order["products"].(primitive.A)
-	var grpcProducts []*pb.ProductItem
-	for _, p := range rawProducts {
-		productMap := p.(primitive.M)
-		grpcProducts = append(grpcProducts, &pb.ProductItem{
-			ProductId: productMap["product_id"].(string),
-			Quantity:  int32(productMap["quantity"].(int32)),
-		})
+	claims, _ := auth.ClaimsFromContext(ctx)
+	if ownerID, _ := order["user_id"].(string); claims == nil || (ownerID != claims.Subject && !claims.HasRole(auth.RoleAdmin) && !claims.HasScope(auth.ScopeOrdersRead)) {
+		return nil, status.Errorf(codes.PermissionDenied, "Not allowed to view this order")
 	}
 
+	grpcProducts := productItemsFromDoc(order["products"])
+
 	return &pb.OrderResponse{
-		Id:         oid.Hex(),
-		UserId:     order["user_id"].(string),
-		Products:   grpcProducts,
-		TotalPrice: order["total_price"].(float64),
+		Id:              oid.Hex(),
+		UserId:          order["user_id"].(string),
+		Products:        grpcProducts,
+		TotalPrice:      order["total_price"].(float64),
+		ShippingAddress: addressFromDoc(order["shipping_address"]),
 	}, nil
 }
 
 func (s *OrderServiceServer) GetOrders(ctx context.Context, req *pb.GetOrdersRequest) (*pb.OrdersResponse, error) {
-	cursor, err := orderCollection.Find(ctx, bson.M{})
+	return findOrders(ctx, bson.M{})
+}
+
+func (s *OrderServiceServer) GetUserOrders(ctx context.Context, req *pb.GetUserOrdersRequest) (*pb.OrdersResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "User id is required")
+	}
+	return findOrders(ctx, bson.M{"user_id": req.UserId})
+}
+
+// AnonymizeUserOrders detaches a user's orders from them as part of erasing
+// the user. The orders themselves are kept for accounting.
+func (s *OrderServiceServer) AnonymizeUserOrders(ctx context.Context, req *pb.AnonymizeUserOrdersRequest) (*pb.AnonymizeUserOrdersResponse, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "User id is required")
+	}
+
+	res, err := orderCollection.UpdateMany(ctx,
+		bson.M{"user_id": req.UserId},
+		bson.M{
+			"$set":   bson.M{"user_id": "", "anonymized_at": time.Now()},
+			"$unset": bson.M{"shipping_address": ""},
+		},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to anonymize orders: %v", err)
+	}
+
+	return &pb.AnonymizeUserOrdersResponse{AnonymizedOrders: res.ModifiedCount}, nil
+}
+
+func findOrders(ctx context.Context, filter bson.M) (*pb.OrdersResponse, error) {
+	cursor, err := orderCollection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -100,22 +277,14 @@ func (s *OrderServiceServer) GetOrders(ctx context.Context, req *pb.GetOrdersReq
 		userId := order["user_id"].(string)
 		totalPrice := order["total_price"].(float64)
 
-		var grpcProducts []*pb.ProductItem
-		if rawProducts, ok := order["products"].(primitive.A); ok {
-			for _, p := range rawProducts {
-				productMap := p.(primitive.M)
-				grpcProducts = append(grpcProducts, &pb.ProductItem{
-					ProductId: productMap["product_id"].(string),
-					Quantity:  int32(productMap["quantity"].(int32)),
-				})
-			}
-		}
+		grpcProducts := productItemsFromDoc(order["products"])
 
 		orders = append(orders, &pb.OrderResponse{
-			Id:         oid.Hex(),
-			UserId:     userId,
-			Products:   grpcProducts,
-			TotalPrice: totalPrice,
+			Id:              oid.Hex(),
+			UserId:          userId,
+			Products:        grpcProducts,
+			TotalPrice:      totalPrice,
+			ShippingAddress: addressFromDoc(order["shipping_address"]),
 		})
 	}
 
@@ -125,3 +294,64 @@ func (s *OrderServiceServer) GetOrders(ctx context.Context, req *pb.GetOrdersReq
 
 	return &pb.OrdersResponse{Orders: orders}, nil
 }
+
+func productItemsFromDoc(v interface{}) []*pb.ProductItem {
+	rawProducts, ok := v.(primitive.A)
+	if !ok {
+		return nil
+	}
+
+	var items []*pb.ProductItem
+	for _, p := range rawProducts {
+		productMap := p.(primitive.M)
+		item := &pb.ProductItem{
+			ProductId: productMap["product_id"].(string),
+			Quantity:  int32(productMap["quantity"].(int32)),
+		}
+		// Orders placed before variants have neither field.
+		item.VariantSku, _ = productMap["variant_sku"].(string)
+		item.UnitPrice, _ = productMap["unit_price"].(float64)
+		items = append(items, item)
+	}
+	return items
+}
+
+// addressDoc snapshots a shipping address into the order, so later edits to
+// the user's address book do not change where past orders were sent.
+func addressDoc(a *pb.Address) bson.M {
+	return bson.M{
+		"address_id":  a.Id,
+		"label":       a.Label,
+		"recipient":   a.Recipient,
+		"line1":       a.Line1,
+		"line2":       a.Line2,
+		"city":        a.City,
+		"region":      a.Region,
+		"postal_code": a.PostalCode,
+		"country":     a.Country,
+		"phone":       a.Phone,
+	}
+}
+
+func addressFromDoc(v interface{}) *pb.Address {
+	doc, ok := v.(primitive.M)
+	if !ok {
+		return nil
+	}
+	field := func(key string) string {
+		s, _ := doc[key].(string)
+		return s
+	}
+	return &pb.Address{
+		Id:         field("address_id"),
+		Label:      field("label"),
+		Recipient:  field("recipient"),
+		Line1:      field("line1"),
+		Line2:      field("line2"),
+		City:       field("city"),
+		Region:     field("region"),
+		PostalCode: field("postal_code"),
+		Country:    field("country"),
+		Phone:      field("phone"),
+	}
+}
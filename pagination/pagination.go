@@ -0,0 +1,92 @@
+// Package pagination implements opaque, cursor-based page tokens for Mongo
+// queries sorted by one field with _id as the tie breaker.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+var ErrInvalidToken = errors.New("invalid page token")
+
+// PageSize clamps a requested page size to the allowed range.
+func PageSize(requested int32) int64 {
+	if requested <= 0 {
+		return DefaultPageSize
+	}
+	if requested > MaxPageSize {
+		return MaxPageSize
+	}
+	return int64(requested)
+}
+
+type cursor struct {
+	SortBy string             `bson:"s"`
+	Value  interface{}        `bson:"v"`
+	ID     primitive.ObjectID `bson:"id"`
+}
+
+type rawCursor struct {
+	SortBy string             `bson:"s"`
+	Value  bson.RawValue      `bson:"v"`
+	ID     primitive.ObjectID `bson:"id"`
+}
+
+// EncodeToken returns the token for the page that follows a document with
+// the given sort value and id.
+func EncodeToken(sortBy string, value interface{}, id primitive.ObjectID) (string, error) {
+	data, err := bson.Marshal(cursor{SortBy: sortBy, Value: value, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// After returns the filter selecting documents that come after the token in
+// the given order. An empty token yields an empty filter.
+func After(token, sortBy string, descending bool) (bson.M, error) {
+	if token == "" {
+		return bson.M{}, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var c rawCursor
+	if err := bson.Unmarshal(data, &c); err != nil || c.SortBy != sortBy {
+		return nil, ErrInvalidToken
+	}
+
+	op := "$gt"
+	if descending {
+		op = "$lt"
+	}
+	if sortBy == "_id" {
+		return bson.M{"_id": bson.M{op: c.ID}}, nil
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{sortBy: bson.M{op: c.Value}},
+		bson.M{sortBy: c.Value, "_id": bson.M{op: c.ID}},
+	}}, nil
+}
+
+// Sort orders by the sort field and then by _id, matching After.
+func Sort(sortBy string, descending bool) bson.D {
+	dir := 1
+	if descending {
+		dir = -1
+	}
+	if sortBy == "_id" {
+		return bson.D{{Key: "_id", Value: dir}}
+	}
+	return bson.D{{Key: sortBy, Value: dir}, {Key: "_id", Value: dir}}
+}
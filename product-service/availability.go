@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Availability policies as stored on products. Products without one are
+// manual.
+const (
+	availabilityManual    = "manual"
+	availabilityAuto      = "auto"
+	availabilityBackorder = "backorder"
+)
+
+func toAvailabilityPolicy(p pb.AvailabilityPolicy) (string, error) {
+	switch p {
+	case pb.AvailabilityPolicy_AVAILABILITY_POLICY_MANUAL:
+		return availabilityManual, nil
+	case pb.AvailabilityPolicy_AVAILABILITY_POLICY_AUTO:
+		return availabilityAuto, nil
+	case pb.AvailabilityPolicy_AVAILABILITY_POLICY_BACKORDER:
+		return availabilityBackorder, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "Unknown availability policy: %v", p)
+	}
+}
+
+func fromAvailabilityPolicy(policy string) pb.AvailabilityPolicy {
+	switch policy {
+	case availabilityAuto:
+		return pb.AvailabilityPolicy_AVAILABILITY_POLICY_AUTO
+	case availabilityBackorder:
+		return pb.AvailabilityPolicy_AVAILABILITY_POLICY_BACKORDER
+	default:
+		return pb.AvailabilityPolicy_AVAILABILITY_POLICY_MANUAL
+	}
+}
+
+func (p *Product) policy() string {
+	if p.AvailabilityPolicy == "" {
+		return availabilityManual
+	}
+	return p.AvailabilityPolicy
+}
+
+// inStock matches the in_stock filter of GetProducts: the product or any of
+// its variants has stock left.
+func (p *Product) inStock() bool {
+	if p.Stock > 0 {
+		return true
+	}
+	for _, v := range p.Variants {
+		if v.Stock > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// availableFor is the availability a policy gives a product; manual keeps
+// what was set by hand.
+func (p *Product) availableFor(policy string) bool {
+	switch policy {
+	case availabilityAuto:
+		return p.inStock()
+	case availabilityBackorder:
+		return true
+	default:
+		return p.IsAvailable
+	}
+}
+
+var (
+	inStockFilter = bson.M{"$or": bson.A{
+		bson.M{"stock": bson.M{"$gt": 0}},
+		bson.M{"variants.stock": bson.M{"$gt": 0}},
+	}}
+	outOfStockFilter = bson.M{
+		"stock":          bson.M{"$lte": 0},
+		"variants.stock": bson.M{"$not": bson.M{"$gt": 0}},
+	}
+)
+
+// availabilityTransitions are the flips a policy makes. Each one is matched
+// against the stored document, so concurrent stock changes cannot leave a
+// product with the wrong availability or record the same flip twice.
+var availabilityTransitions = []struct {
+	policy    string
+	condition bson.M
+	to        bool
+}{
+	{availabilityAuto, outOfStockFilter, false},
+	{availabilityAuto, inStockFilter, true},
+	{availabilityBackorder, bson.M{}, true},
+}
+
+// syncAvailability brings is_available in line with the product's policy
+// and stock, recording a product event when it changes. It reports whether
+// the product was changed.
+func syncAvailability(ctx context.Context, id primitive.ObjectID, reason string) (bool, error) {
+	for _, t := range availabilityTransitions {
+		filter := bson.M{"_id": id, "availability_policy": t.policy, "is_available": !t.to}
+		for key, value := range t.condition {
+			filter[key] = value
+		}
+
+		res, err := productCollection.UpdateOne(ctx, filter, bson.M{
+			"$set": bson.M{"is_available": t.to, "updated_at": time.Now()},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return false, err
+		}
+		if res.ModifiedCount > 0 {
+			return true, recordAvailabilityChange(ctx, id, t.to, reason)
+		}
+	}
+	return false, nil
+}
+
+// syncAvailabilityAfterStock is used where stock moves as a side effect, so
+// a failure to flip availability does not undo the stock change.
+func syncAvailabilityAfterStock(ctx context.Context, id primitive.ObjectID, reason string) {
+	if _, err := syncAvailability(ctx, id, reason); err != nil {
+		log.Printf("Failed to update availability of product %s: %v", id.Hex(), err)
+	}
+}
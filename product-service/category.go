@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxCategoryName = 100
+
+// Category is a document in the categories collection. Ancestors lists the
+// ids from the top-level category down to the parent, so a subtree can be
+// found with a single query on ancestors.
+type Category struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty"`
+	Name      string               `bson:"name"`
+	ParentID  *primitive.ObjectID  `bson:"parent_id"`
+	Ancestors []primitive.ObjectID `bson:"ancestors"`
+	CreatedAt time.Time            `bson:"created_at"`
+	UpdatedAt time.Time            `bson:"updated_at"`
+}
+
+func categoryName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxCategoryName {
+		return "", status.Errorf(codes.InvalidArgument, "Name is required and must be at most %d characters", maxCategoryName)
+	}
+	return name, nil
+}
+
+// parseParentID reads an optional parent id; empty means the top level.
+func parseParentID(id string) (*primitive.ObjectID, error) {
+	if id == "" {
+		return nil, nil
+	}
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid parent id: %v", err)
+	}
+	return &oid, nil
+}
+
+func findCategory(ctx context.Context, id primitive.ObjectID) (*Category, error) {
+	var category Category
+	err := categoryCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&category)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "Category not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch category: %v", err)
+	}
+	return &category, nil
+}
+
+// childAncestors returns the ancestors of a category placed under parent.
+func childAncestors(parent *Category) []primitive.ObjectID {
+	if parent == nil {
+		return []primitive.ObjectID{}
+	}
+	ancestors := append([]primitive.ObjectID{}, parent.Ancestors...)
+	return append(ancestors, parent.ID)
+}
+
+// subtreeIDs returns the id of a category and of every category below it.
+func subtreeIDs(ctx context.Context, id primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := categoryCollection.Find(ctx, bson.M{"ancestors": id})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch categories: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	ids := []primitive.ObjectID{id}
+	for cursor.Next(ctx) {
+		var category Category
+		if err := cursor.Decode(&category); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode category: %v", err)
+		}
+		ids = append(ids, category.ID)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch categories: %v", err)
+	}
+	return ids, nil
+}
+
+// categoryPaths resolves the breadcrumb path of each given category id with
+// one query for all the categories involved.
+func categoryPaths(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID][]*pb.CategoryCrumb, error) {
+	paths := map[primitive.ObjectID][]*pb.CategoryCrumb{}
+	if len(ids) == 0 {
+		return paths, nil
+	}
+
+	byID, err := loadCategories(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var ancestorIDs []primitive.ObjectID
+	for _, category := range byID {
+		ancestorIDs = append(ancestorIDs, category.Ancestors...)
+	}
+	ancestors, err := loadCategories(ctx, ancestorIDs)
+	if err != nil {
+		return nil, err
+	}
+	for id, category := range ancestors {
+		byID[id] = category
+	}
+
+	for _, id := range ids {
+		category, ok := byID[id]
+		if !ok {
+			continue
+		}
+		var path []*pb.CategoryCrumb
+		for _, ancestorID := range append(category.Ancestors, category.ID) {
+			if ancestor, ok := byID[ancestorID]; ok {
+				path = append(path, &pb.CategoryCrumb{Id: ancestor.ID.Hex(), Name: ancestor.Name})
+			}
+		}
+		paths[id] = path
+	}
+	return paths, nil
+}
+
+func loadCategories(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*Category, error) {
+	byID := map[primitive.ObjectID]*Category{}
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	cursor, err := categoryCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch categories: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var category Category
+		if err := cursor.Decode(&category); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode category: %v", err)
+		}
+		byID[category.ID] = &category
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch categories: %v", err)
+	}
+	return byID, nil
+}
+
+func toCategoryResponse(c *Category, path []*pb.CategoryCrumb) *pb.Category {
+	resp := &pb.Category{
+		Id:        c.ID.Hex(),
+		Name:      c.Name,
+		Path:      path,
+		CreatedAt: c.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: c.UpdatedAt.Format(time.RFC3339),
+	}
+	if c.ParentID != nil {
+		resp.ParentId = c.ParentID.Hex()
+	}
+	return resp
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type CategoryServiceServer struct {
+	pb.UnimplementedCategoryServiceServer
+}
+
+func (s *CategoryServiceServer) CreateCategory(ctx context.Context, req *pb.CreateCategoryRequest) (*pb.Category, error) {
+	name, err := categoryName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	parentID, err := parseParentID(req.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *Category
+	if parentID != nil {
+		if parent, err = findCategory(ctx, *parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	category := &Category{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		ParentID:  parentID,
+		Ancestors: childAncestors(parent),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err = categoryCollection.InsertOne(ctx, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Errorf(codes.AlreadyExists, "A category named %s already exists here", name)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to create category: %v", err)
+	}
+
+	return categoryResponse(ctx, category)
+}
+
+func (s *CategoryServiceServer) GetCategory(ctx context.Context, req *pb.GetCategoryRequest) (*pb.Category, error) {
+	oid, err := primitive.ObjectIDFromHex(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	category, err := findCategory(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	return categoryResponse(ctx, category)
+}
+
+func (s *CategoryServiceServer) ListCategories(ctx context.Context, req *pb.ListCategoriesRequest) (*pb.CategoriesResponse, error) {
+	parentID, err := parseParentID(req.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := categoryCollection.Find(ctx, bson.M{"parent_id": parentID}, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch categories: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var categories []*Category
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var category Category
+		if err := cursor.Decode(&category); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode category: %v", err)
+		}
+		categories = append(categories, &category)
+		ids = append(ids, category.ID)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch categories: %v", err)
+	}
+
+	paths, err := categoryPaths(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.CategoriesResponse{}
+	for _, category := range categories {
+		resp.Categories = append(resp.Categories, toCategoryResponse(category, paths[category.ID]))
+	}
+	return resp, nil
+}
+
+// RenameCategory also renames the category on its products, which keep the
+// name for filtering and facets.
+func (s *CategoryServiceServer) RenameCategory(ctx context.Context, req *pb.RenameCategoryRequest) (*pb.Category, error) {
+	oid, err := primitive.ObjectIDFromHex(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	name, err := categoryName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	res, err := categoryCollection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{
+		"$set": bson.M{"name": name, "updated_at": now},
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Errorf(codes.AlreadyExists, "A category named %s already exists here", name)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to rename category: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "Category not found")
+	}
+
+	// The copied name is part of each product, so their versions move too.
+	_, err = productCollection.UpdateMany(ctx, bson.M{"category_id": oid}, bson.M{
+		"$set": bson.M{"category": name, "updated_at": now},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to rename category on products: %v", err)
+	}
+
+	category, err := findCategory(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	return categoryResponse(ctx, category)
+}
+
+// MoveCategory re-parents a category together with everything below it.
+func (s *CategoryServiceServer) MoveCategory(ctx context.Context, req *pb.MoveCategoryRequest) (*pb.Category, error) {
+	oid, err := primitive.ObjectIDFromHex(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	parentID, err := parseParentID(req.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := findCategory(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *Category
+	if parentID != nil {
+		if parent, err = findCategory(ctx, *parentID); err != nil {
+			return nil, err
+		}
+		if parent.ID == oid {
+			return nil, status.Errorf(codes.InvalidArgument, "A category cannot be its own parent")
+		}
+		for _, ancestor := range parent.Ancestors {
+			if ancestor == oid {
+				return nil, status.Errorf(codes.InvalidArgument, "A category cannot be moved below one of its descendants")
+			}
+		}
+	}
+
+	ancestors := childAncestors(parent)
+	now := time.Now()
+	_, err = categoryCollection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{
+		"$set": bson.M{"parent_id": parentID, "ancestors": ancestors, "updated_at": now},
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, status.Errorf(codes.AlreadyExists, "A category named %s already exists there", category.Name)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to move category: %v", err)
+	}
+
+	// Descendants keep the part of their ancestors from the moved category
+	// down and get the new prefix in front of it.
+	_, err = categoryCollection.UpdateMany(ctx, bson.M{"ancestors": oid}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"ancestors": bson.M{"$concatArrays": bson.A{
+				ancestors,
+				bson.M{"$slice": bson.A{
+					"$ancestors",
+					bson.M{"$indexOfArray": bson.A{"$ancestors", oid}},
+					bson.M{"$size": "$ancestors"},
+				}},
+			}},
+			"updated_at": now,
+		}}},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to move subcategories: %v", err)
+	}
+
+	category, err = findCategory(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	return categoryResponse(ctx, category)
+}
+
+// DeleteCategory only removes empty leaves, so no product or subcategory is
+// left pointing at a missing category.
+func (s *CategoryServiceServer) DeleteCategory(ctx context.Context, req *pb.DeleteCategoryRequest) (*pb.DeleteCategoryResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	children, err := categoryCollection.CountDocuments(ctx, bson.M{"parent_id": oid})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to count subcategories: %v", err)
+	}
+	if children > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "Category has subcategories")
+	}
+
+	products, err := productCollection.CountDocuments(ctx, bson.M{"category_id": oid})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to count products: %v", err)
+	}
+	if products > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "Category still has %d products", products)
+	}
+
+	res, err := categoryCollection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete category: %v", err)
+	}
+	if res.DeletedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "Category not found")
+	}
+
+	return &pb.DeleteCategoryResponse{
+		Id:      req.Id,
+		Success: true,
+	}, nil
+}
+
+func categoryResponse(ctx context.Context, category *Category) (*pb.Category, error) {
+	paths, err := categoryPaths(ctx, []primitive.ObjectID{category.ID})
+	if err != nil {
+		return nil, err
+	}
+	return toCategoryResponse(category, paths[category.ID]), nil
+}
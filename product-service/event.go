@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	eventAvailabilityChanged = "availability_changed"
+	eventLowStock            = "low_stock"
+)
+
+// ProductEvent is a document in the product_events collection, an
+// append-only log of what happened to a product and why.
+type ProductEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ProductID primitive.ObjectID `bson:"product_id"`
+	Type      string             `bson:"type"`
+	Reason    string             `bson:"reason,omitempty"`
+	Available *bool              `bson:"available,omitempty"`
+	Stock     *int32             `bson:"stock,omitempty"`
+	Threshold *int32             `bson:"reorder_threshold,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+func recordEvent(ctx context.Context, event ProductEvent) error {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+	_, err := productEventCollection.InsertOne(ctx, event)
+	return err
+}
+
+func recordAvailabilityChange(ctx context.Context, productID primitive.ObjectID, available bool, reason string) error {
+	return recordEvent(ctx, ProductEvent{
+		ProductID: productID,
+		Type:      eventAvailabilityChanged,
+		Reason:    reason,
+		Available: &available,
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes of every collection product-service owns.
+func EnsureIndexes(ctx context.Context) error {
+	_, err := productCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// SearchProducts; a name match weighs ten times a description match.
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("product_text").
+				SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "description", Value: 1}}),
+		},
+		{Keys: bson.D{{Key: "category_id", Value: 1}}},
+		// A SKU belongs to one variant across all products.
+		{
+			Keys: bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().
+				SetName("variant_sku_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = categoryCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// Sibling categories need distinct names, ignoring case.
+		{
+			Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+		},
+		// Subtree lookups.
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The reservation sweeper looks for pending reservations past expiry.
+	_, err = reservationCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// A product's events, newest first.
+	_, err = productEventCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The price scheduler looks for schedules due to start and sales due to end.
+	_, err = priceScheduleCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "starts_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "ends_at", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = priceHistoryCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// GetPriceHistory pages through a product's changes, newest first.
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "changed_at", Value: -1}, {Key: "_id", Value: -1}}},
+		// A retried schedule records each of its changes once.
+		{
+			Keys: bson.D{{Key: "schedule_id", Value: 1}, {Key: "reason", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"schedule_id": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// BackfillVersions gives products created before versioning their first
+// version, so they can be updated like any other.
+func BackfillVersions(ctx context.Context) error {
+	_, err := productCollection.UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": int64(1)}},
+	)
+	return err
+}
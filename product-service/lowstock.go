@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultVelocityWindowDays = 30
+	maxVelocityWindowDays     = 365
+)
+
+// totalStock is what reorder thresholds are compared against: the product's
+// own stock plus that of all its variants.
+func (p *Product) totalStock() int32 {
+	total := p.Stock
+	for _, v := range p.Variants {
+		total += v.Stock
+	}
+	return total
+}
+
+// totalStockExpr computes totalStock inside a query. A missing stock counts
+// as zero, as it decodes in Go; $add would make the sum null instead.
+var totalStockExpr = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$stock", 0}}, bson.M{"$sum": "$variants.stock"}}}
+
+// checkLowStock alerts when a stock decrease takes a product from above its
+// reorder threshold to at or below it. Staying low does not alert again
+// until the product has been restocked past the threshold.
+func checkLowStock(ctx context.Context, before int32, product *Product, reason string) {
+	threshold := product.ReorderThreshold
+	after := product.totalStock()
+	if threshold <= 0 || before <= threshold || after > threshold {
+		return
+	}
+
+	err := recordEvent(ctx, ProductEvent{
+		ProductID: product.ID,
+		Type:      eventLowStock,
+		Reason:    reason,
+		Stock:     &after,
+		Threshold: &threshold,
+	})
+	if err != nil {
+		log.Printf("Failed to record low-stock event for product %s: %v", product.ID.Hex(), err)
+	}
+
+	err = notifier.NotifyLowStock(ctx, LowStockAlert{
+		ProductID: product.ID.Hex(),
+		Name:      product.Name,
+		Stock:     after,
+		Threshold: threshold,
+		Reason:    reason,
+		At:        time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to send low-stock alert for product %s: %v", product.ID.Hex(), err)
+	}
+}
+
+// unitsSold sums the quantities ordered per product since the given time.
+// Orders carry no creation date, so the timestamp in their ObjectID is used.
+func unitsSold(ctx context.Context, ids []string, since time.Time) (map[string]int64, error) {
+	sold := map[string]int64{}
+	if len(ids) == 0 {
+		return sold, nil
+	}
+
+	cursor, err := orderCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id":                 bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)},
+			"products.product_id": bson.M{"$in": ids},
+		}}},
+		{{Key: "$unwind", Value: "$products"}},
+		{{Key: "$match", Value: bson.M{"products.product_id": bson.M{"$in": ids}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$products.product_id", "units": bson.M{"$sum": "$products.quantity"}}}},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to compute sales: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row struct {
+			ProductID string `bson:"_id"`
+			Units     int64  `bson:"units"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode sales: %v", err)
+		}
+		sold[row.ProductID] = row.Units
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to compute sales: %v", err)
+	}
+	return sold, nil
+}
+
+// sortLowStockItems puts the products that will run out first on top;
+// products that did not sell come last, lowest stock first.
+func sortLowStockItems(items []*pb.LowStockItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if (a.DaysOfCover == nil) != (b.DaysOfCover == nil) {
+			return a.DaysOfCover != nil
+		}
+		if a.DaysOfCover != nil && *a.DaysOfCover != *b.DaysOfCover {
+			return *a.DaysOfCover < *b.DaysOfCover
+		}
+		return a.Stock < b.Stock
+	})
+}
@@ -3,12 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"goFinalProject/auth"
+	"goFinalProject/etag"
 	pb "goFinalProject/proto/proto"
 
 	"github.com/gorilla/mux"
@@ -16,9 +20,20 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 var productCollection *mongo.Collection
+var categoryCollection *mongo.Collection
+var reservationCollection *mongo.Collection
+var productEventCollection *mongo.Collection
+var priceScheduleCollection *mongo.Collection
+var priceHistoryCollection *mongo.Collection
+
+// orderCollection belongs to order-service; it is only read here, to work
+// out sales velocity for the low-stock report.
+var orderCollection *mongo.Collection
+var userClient pb.UserServiceClient
 
 func init() {
 	if err := godotenv.Load(); err != nil {
@@ -38,7 +53,38 @@ func InitMongo() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	productCollection = client.Database("go_microservices").Collection("products")
+	db := client.Database("go_microservices")
+	productCollection = db.Collection("products")
+	categoryCollection = db.Collection("categories")
+	reservationCollection = db.Collection("reservations")
+	productEventCollection = db.Collection("product_events")
+	priceScheduleCollection = db.Collection("price_schedules")
+	priceHistoryCollection = db.Collection("price_history")
+	orderCollection = db.Collection("orders")
+}
+
+func initGRPCClients() {
+	userConn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("Failed to connect to user-service: %v", err)
+	}
+	userClient = pb.NewUserServiceClient(userConn)
+}
+
+// validateAPIKey checks keys presented to this service against user-service,
+// which owns them.
+func validateAPIKey(ctx context.Context, key string) (*auth.Claims, error) {
+	principal, err := userClient.AuthenticateAPIKey(ctx, &pb.AuthenticateAPIKeyRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		APIKeyID: principal.KeyId,
+		Scopes:   principal.Scopes,
+	}
+	claims.Subject = principal.UserId
+	return claims, nil
 }
 
 func grpcDial() *grpc.ClientConn {
@@ -52,7 +98,7 @@ func grpcDial() *grpc.ClientConn {
 func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
 	var req pb.CreateProductRequest
 	json.NewDecoder(r.Body).Decode(&req)
-	resp, err := pb.NewProductServiceClient(grpcDial()).CreateProduct(context.Background(), &req)
+	resp, err := pb.NewProductServiceClient(grpcDial()).CreateProduct(auth.OutgoingContext(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -62,16 +108,59 @@ func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
 
 func GetProductHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	resp, err := pb.NewProductServiceClient(grpcDial()).GetProduct(context.Background(), &pb.GetProductRequest{Id: id})
+	resp, err := pb.NewProductServiceClient(grpcDial()).GetProduct(auth.OutgoingContext(r), &pb.GetProductRequest{Id: id})
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
+	w.Header().Set("ETag", etag.Format(resp.Version))
 	json.NewEncoder(w).Encode(resp)
 }
 
 func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
-	resp, err := pb.NewProductServiceClient(grpcDial()).GetProducts(context.Background(), &pb.GetProductsRequest{})
+	query := r.URL.Query()
+	req := pb.GetProductsRequest{
+		PageToken:  query.Get("page_token"),
+		Category:   query.Get("category"),
+		CategoryId: query.Get("category_id"),
+		InStock:    query.Get("in_stock") == "true",
+		SortBy:     query.Get("sort_by"),
+		Descending: query.Get("order") == "desc",
+	}
+	if pageSize := query.Get("page_size"); pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil {
+			http.Error(w, "Invalid page_size", http.StatusBadRequest)
+			return
+		}
+		req.PageSize = int32(size)
+	}
+	if minPrice := query.Get("min_price"); minPrice != "" {
+		price, err := strconv.ParseFloat(minPrice, 64)
+		if err != nil {
+			http.Error(w, "Invalid min_price", http.StatusBadRequest)
+			return
+		}
+		req.MinPrice = &price
+	}
+	if maxPrice := query.Get("max_price"); maxPrice != "" {
+		price, err := strconv.ParseFloat(maxPrice, 64)
+		if err != nil {
+			http.Error(w, "Invalid max_price", http.StatusBadRequest)
+			return
+		}
+		req.MaxPrice = &price
+	}
+	if isAvailable := query.Get("is_available"); isAvailable != "" {
+		available, err := strconv.ParseBool(isAvailable)
+		if err != nil {
+			http.Error(w, "Invalid is_available", http.StatusBadRequest)
+			return
+		}
+		req.IsAvailable = &available
+	}
+
+	resp, err := pb.NewProductServiceClient(grpcDial()).GetProducts(auth.OutgoingContext(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -79,13 +168,194 @@ func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// UpdateProductHandler serves PUT and PATCH alike. Only the fields in the
+// body's update_mask change, or without one the fields present in the body,
+// so nothing a client left out is cleared.
 func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 	var req pb.UpdateProductRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Id = mux.Vars(r)["id"]
+	if len(req.GetUpdateMask().GetPaths()) == 0 {
+		req.UpdateMask = &fieldmaskpb.FieldMask{}
+		for field := range fields {
+			if field == "id" || field == "update_mask" || field == "version" {
+				continue
+			}
+			req.UpdateMask.Paths = append(req.UpdateMask.Paths, field)
+		}
+	}
+	if len(req.UpdateMask.Paths) == 0 {
+		http.Error(w, "No fields to update", http.StatusBadRequest)
+		return
+	}
+	version, err := etag.IfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPreconditionRequired)
+		return
+	}
+	req.Version = version
+
+	resp, err := pb.NewProductServiceClient(grpcDial()).UpdateProduct(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), etag.ErrorStatus(err))
+		return
+	}
+	w.Header().Set("ETag", etag.Format(resp.Version))
+	json.NewEncoder(w).Encode(resp)
+}
+
+func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var req pb.CreateCategoryRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	resp, err := pb.NewCategoryServiceClient(grpcDial()).CreateCategory(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func GetCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	resp, err := pb.NewCategoryServiceClient(grpcDial()).GetCategory(auth.OutgoingContext(r), &pb.GetCategoryRequest{Id: id})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	parentID := r.URL.Query().Get("parent_id")
+	resp, err := pb.NewCategoryServiceClient(grpcDial()).ListCategories(auth.OutgoingContext(r), &pb.ListCategoriesRequest{ParentId: parentID})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func RenameCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var req pb.RenameCategoryRequest
 	json.NewDecoder(r.Body).Decode(&req)
+	req.Id = mux.Vars(r)["id"]
+	resp, err := pb.NewCategoryServiceClient(grpcDial()).RenameCategory(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
 
+func MoveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var req pb.MoveCategoryRequest
+	json.NewDecoder(r.Body).Decode(&req)
 	req.Id = mux.Vars(r)["id"]
+	resp, err := pb.NewCategoryServiceClient(grpcDial()).MoveCategory(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	resp, err := pb.NewCategoryServiceClient(grpcDial()).DeleteCategory(auth.OutgoingContext(r), &pb.DeleteCategoryRequest{Id: id})
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func SearchProductsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := pb.SearchProductsRequest{
+		Query:     query.Get("q"),
+		PageToken: query.Get("page_token"),
+		Category:  query.Get("category"),
+	}
+	if pageSize := query.Get("page_size"); pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil {
+			http.Error(w, "Invalid page_size", http.StatusBadRequest)
+			return
+		}
+		req.PageSize = int32(size)
+	}
+
+	resp, err := pb.NewProductServiceClient(grpcDial()).SearchProducts(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func LowStockReportHandler(w http.ResponseWriter, r *http.Request) {
+	var req pb.LowStockReportRequest
+	if windowDays := r.URL.Query().Get("window_days"); windowDays != "" {
+		days, err := strconv.Atoi(windowDays)
+		if err != nil {
+			http.Error(w, "Invalid window_days", http.StatusBadRequest)
+			return
+		}
+		req.WindowDays = int32(days)
+	}
+
+	resp, err := pb.NewProductServiceClient(grpcDial()).GetLowStockReport(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func SchedulePriceChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var req pb.SchedulePriceChangeRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	req.ProductId = mux.Vars(r)["id"]
+
+	resp, err := pb.NewProductServiceClient(grpcDial()).SchedulePriceChange(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func GetPriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := pb.GetPriceHistoryRequest{
+		ProductId: mux.Vars(r)["id"],
+		PageToken: query.Get("page_token"),
+	}
+	if pageSize := query.Get("page_size"); pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil {
+			http.Error(w, "Invalid page_size", http.StatusBadRequest)
+			return
+		}
+		req.PageSize = int32(size)
+	}
 
-	resp, err := pb.NewProductServiceClient(grpcDial()).UpdateProduct(context.Background(), &req)
+	resp, err := pb.NewProductServiceClient(grpcDial()).GetPriceHistory(auth.OutgoingContext(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -95,7 +365,7 @@ func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
 
 func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	resp, err := pb.NewProductServiceClient(grpcDial()).DeleteProduct(context.Background(), &pb.DeleteProductRequest{Id: id})
+	resp, err := pb.NewProductServiceClient(grpcDial()).DeleteProduct(auth.OutgoingContext(r), &pb.DeleteProductRequest{Id: id})
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -103,15 +373,60 @@ func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+var authPolicy = auth.Policy{
+	pb.ProductService_CreateProduct_FullMethodName:  {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.ProductService_GetProduct_FullMethodName:     {Public: true},
+	pb.ProductService_GetProducts_FullMethodName:    {Public: true},
+	pb.ProductService_SearchProducts_FullMethodName: {Public: true},
+	// Stock is reserved by order-service while it places an order, on behalf
+	// of the user named in the request; users cannot hold stock themselves.
+	pb.ProductService_ReserveStock_FullMethodName:       {Roles: []string{auth.RoleAdmin, auth.RoleService}},
+	pb.ProductService_CommitReservation_FullMethodName:  {Roles: []string{auth.RoleAdmin, auth.RoleService}},
+	pb.ProductService_ReleaseReservation_FullMethodName: {Roles: []string{auth.RoleAdmin, auth.RoleService}},
+
+	pb.ProductService_GetLowStockReport_FullMethodName:   {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.ProductService_SchedulePriceChange_FullMethodName: {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.ProductService_GetPriceHistory_FullMethodName:     {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+
+	pb.CategoryService_CreateCategory_FullMethodName: {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.CategoryService_GetCategory_FullMethodName:    {Public: true},
+	pb.CategoryService_ListCategories_FullMethodName: {Public: true},
+	pb.CategoryService_RenameCategory_FullMethodName: {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.CategoryService_MoveCategory_FullMethodName:   {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.CategoryService_DeleteCategory_FullMethodName: {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.ProductService_UpdateProduct_FullMethodName:   {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+	pb.ProductService_DeleteProduct_FullMethodName:   {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeProductsWrite}},
+}
+
 func main() {
 	InitMongo()
+	InitNotifier()
+	initGRPCClients()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create product indexes: %v", err)
+	}
+	if err := BackfillVersions(ctx); err != nil {
+		log.Fatalf("Failed to backfill product versions: %v", err)
+	}
+	cancel()
+
+	go sweepReservations(reservationSweepInterval)
+	go runPriceScheduler(priceScheduleInterval)
+
+	secret, err := auth.Secret()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	lis, err := net.Listen("tcp", ":50052")
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryServerInterceptor(secret, validateAPIKey, authPolicy)))
 	pb.RegisterProductServiceServer(grpcServer, &ProductServiceServer{})
+	pb.RegisterCategoryServiceServer(grpcServer, &CategoryServiceServer{})
 
 	go func() {
 		log.Println("gRPC server started on port :50052")
@@ -122,10 +437,20 @@ func main() {
 
 	r := mux.NewRouter()
 	r.HandleFunc("/api/products", CreateProductHandler).Methods("POST")
+	r.HandleFunc("/api/products/search", SearchProductsHandler).Methods("GET")
+	r.HandleFunc("/api/products/low-stock", LowStockReportHandler).Methods("GET")
 	r.HandleFunc("/api/products/{id}", GetProductHandler).Methods("GET")
 	r.HandleFunc("/api/products", GetProductsHandler).Methods("GET")
-	r.HandleFunc("/api/products/{id}", UpdateProductHandler).Methods("PUT")
+	r.HandleFunc("/api/products/{id}", UpdateProductHandler).Methods("PUT", "PATCH")
 	r.HandleFunc("/api/products/{id}", DeleteProductHandler).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/price-schedules", SchedulePriceChangeHandler).Methods("POST")
+	r.HandleFunc("/api/products/{id}/price-history", GetPriceHistoryHandler).Methods("GET")
+	r.HandleFunc("/api/categories", CreateCategoryHandler).Methods("POST")
+	r.HandleFunc("/api/categories", ListCategoriesHandler).Methods("GET")
+	r.HandleFunc("/api/categories/{id}", GetCategoryHandler).Methods("GET")
+	r.HandleFunc("/api/categories/{id}/name", RenameCategoryHandler).Methods("PUT")
+	r.HandleFunc("/api/categories/{id}/parent", MoveCategoryHandler).Methods("PUT")
+	r.HandleFunc("/api/categories/{id}", DeleteCategoryHandler).Methods("DELETE")
 
 	log.Println("HTTP server started at :8081")
 	http.ListenAndServe(":8081", r)
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+)
+
+// LowStockAlert is sent when a product's stock falls to its reorder
+// threshold.
+type LowStockAlert struct {
+	ProductID string    `json:"product_id"`
+	Name      string    `json:"name"`
+	Stock     int32     `json:"stock"`
+	Threshold int32     `json:"reorder_threshold"`
+	Reason    string    `json:"reason"`
+	At        time.Time `json:"at"`
+}
+
+// Notifier tells merchandising about products that are running out.
+type Notifier interface {
+	NotifyLowStock(ctx context.Context, alert LowStockAlert) error
+}
+
+// LogNotifier writes alerts to the service log.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyLowStock(ctx context.Context, alert LowStockAlert) error {
+	log.Printf("Low stock: product %s (%s) is at %d, reorder threshold %d", alert.ProductID, alert.Name, alert.Stock, alert.Threshold)
+	return nil
+}
+
+// FileNotifier appends each alert as a JSON line to a file, for local
+// development.
+type FileNotifier struct {
+	Path string
+}
+
+func (n FileNotifier) NotifyLowStock(ctx context.Context, alert LowStockAlert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+var notifier Notifier
+
+func InitNotifier() {
+	if path := os.Getenv("LOW_STOCK_ALERT_FILE"); path != "" {
+		notifier = FileNotifier{Path: path}
+		return
+	}
+	notifier = LogNotifier{}
+}
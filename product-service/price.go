@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	priceScheduleInterval = time.Minute
+
+	schedulePending    = "pending"
+	scheduleActive     = "active"
+	scheduleDone       = "done"
+	scheduleSuperseded = "superseded"
+)
+
+// Sale is the running sale of a product, embedded in its document. The
+// scheduler removes it once it ends.
+type Sale struct {
+	ScheduleID primitive.ObjectID `bson:"schedule_id"`
+	Price      float64            `bson:"price"`
+	EndsAt     time.Time          `bson:"ends_at"`
+}
+
+// PriceSchedule is a document in the price_schedules collection. Without
+// EndsAt it changes the list price when it starts; with EndsAt it is a sale.
+type PriceSchedule struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ProductID primitive.ObjectID `bson:"product_id"`
+	Price     float64            `bson:"price"`
+	StartsAt  time.Time          `bson:"starts_at"`
+	EndsAt    *time.Time         `bson:"ends_at,omitempty"`
+	Status    string             `bson:"status"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// PriceHistoryEntry is a document in the price_history collection, written
+// whenever the list or effective price of a product, or the price of one of
+// its variants, changes.
+type PriceHistoryEntry struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty"`
+	ProductID      primitive.ObjectID  `bson:"product_id"`
+	ListPrice      float64             `bson:"list_price"`
+	EffectivePrice float64             `bson:"effective_price"`
+	VariantPrices  map[string]float64  `bson:"variant_prices,omitempty"`
+	Reason         string              `bson:"reason"`
+	ScheduleID     *primitive.ObjectID `bson:"schedule_id,omitempty"`
+	ChangedAt      time.Time           `bson:"changed_at"`
+}
+
+// effectivePrice is what a unit costs at the given time. A sale past its
+// end no longer counts, even before the scheduler has removed it.
+func (p *Product) effectivePrice(now time.Time) float64 {
+	if p.Sale != nil && now.Before(p.Sale.EndsAt) {
+		return p.Sale.Price
+	}
+	return p.Price
+}
+
+// variantPrices maps the SKU of every variant with its own price to that
+// price.
+func (p *Product) variantPrices() map[string]float64 {
+	prices := map[string]float64{}
+	for _, variant := range p.Variants {
+		if variant.Price != nil {
+			prices[variant.SKU] = *variant.Price
+		}
+	}
+	return prices
+}
+
+// recordPriceChange writes an entry to the price history. A schedule gets one
+// entry per reason, however often the scheduler retries it.
+func recordPriceChange(ctx context.Context, p *Product, reason string, scheduleID *primitive.ObjectID) error {
+	now := time.Now()
+	entry := PriceHistoryEntry{
+		ID:             primitive.NewObjectID(),
+		ProductID:      p.ID,
+		ListPrice:      p.Price,
+		EffectivePrice: p.effectivePrice(now),
+		VariantPrices:  p.variantPrices(),
+		Reason:         reason,
+		ScheduleID:     scheduleID,
+		ChangedAt:      now,
+	}
+	if scheduleID == nil {
+		_, err := priceHistoryCollection.InsertOne(ctx, entry)
+		return err
+	}
+
+	_, err := priceHistoryCollection.UpdateOne(ctx,
+		bson.M{"schedule_id": *scheduleID, "reason": reason},
+		bson.M{"$setOnInsert": entry},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent retry recorded it first.
+		return nil
+	}
+	return err
+}
+
+// claimSchedule moves one schedule matching filter to a new status. Like
+// reservations, a schedule is claimed before it is applied, so it is never
+// applied twice; if applying it fails, unclaimSchedule hands it back.
+func claimSchedule(ctx context.Context, filter bson.M, to string) (*PriceSchedule, error) {
+	var schedule PriceSchedule
+	err := priceScheduleCollection.FindOneAndUpdate(ctx, filter, bson.M{
+		"$set": bson.M{"status": to, "updated_at": time.Now()},
+	}).Decode(&schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// unclaimSchedule moves a claimed schedule back to the status it was claimed
+// from, so the next run applies it again. It runs on a fresh context, since
+// the run's own may be what failed.
+func unclaimSchedule(id primitive.ObjectID, from, to string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := claimSchedule(ctx, bson.M{"_id": id, "status": to}, from); err != nil {
+		log.Printf("Failed to unclaim price schedule %s: %v", id.Hex(), err)
+	}
+}
+
+// startSchedule applies the earliest pending schedule that is due, so
+// schedules that piled up are applied in the order they were meant to start.
+// Sales that ended before they could start are closed without touching the
+// product.
+func startSchedule(ctx context.Context, now time.Time) error {
+	var schedule PriceSchedule
+	opts := options.FindOne().SetSort(bson.D{{Key: "starts_at", Value: 1}, {Key: "_id", Value: 1}})
+	err := priceScheduleCollection.FindOne(ctx, bson.M{"status": schedulePending, "starts_at": bson.M{"$lte": now}}, opts).Decode(&schedule)
+	if err != nil {
+		return err
+	}
+
+	to := scheduleDone
+	set := bson.M{"updated_at": now}
+	reason := "scheduled"
+	if schedule.EndsAt != nil && schedule.EndsAt.After(now) {
+		to = scheduleActive
+		set["sale"] = Sale{ScheduleID: schedule.ID, Price: schedule.Price, EndsAt: *schedule.EndsAt}
+		reason = "sale_started"
+	} else if schedule.EndsAt == nil {
+		set["price"] = schedule.Price
+	}
+	_, err = claimSchedule(ctx, bson.M{"_id": schedule.ID, "status": schedulePending}, to)
+	if err == mongo.ErrNoDocuments {
+		// Another instance got to it first.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(set) == 1 {
+		return nil
+	}
+
+	if err := applySchedule(ctx, &schedule, set, reason); err != nil {
+		unclaimSchedule(schedule.ID, schedulePending, to)
+		return err
+	}
+	return nil
+}
+
+// applySchedule writes a claimed schedule to its product and records the
+// change. Every step can be repeated, so a schedule handed back after a
+// failure is applied again on the next run and its history is not lost.
+func applySchedule(ctx context.Context, schedule *PriceSchedule, set bson.M, reason string) error {
+	res, err := productCollection.UpdateOne(ctx, bson.M{"_id": schedule.ProductID}, bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		// The product was deleted; there is nothing left to price.
+		return nil
+	}
+
+	// A sale starting ends the one it replaces; a product has one sale at
+	// a time.
+	if _, ok := set["sale"]; ok {
+		_, err := priceScheduleCollection.UpdateMany(ctx,
+			bson.M{"product_id": schedule.ProductID, "status": scheduleActive, "_id": bson.M{"$ne": schedule.ID}},
+			bson.M{"$set": bson.M{"status": scheduleSuperseded, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	product, err := findProduct(ctx, bson.M{"_id": schedule.ProductID})
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return recordPriceChange(ctx, product, reason, &schedule.ID)
+}
+
+// endSale removes one sale whose end has passed from its product.
+func endSale(ctx context.Context, now time.Time) error {
+	schedule, err := claimSchedule(ctx, bson.M{"status": scheduleActive, "ends_at": bson.M{"$lte": now}}, scheduleDone)
+	if err != nil {
+		return err
+	}
+
+	if err := finishSale(ctx, schedule, now); err != nil {
+		unclaimSchedule(schedule.ID, scheduleActive, scheduleDone)
+		return err
+	}
+	return nil
+}
+
+// finishSale takes an ended sale off its product and records the change. On
+// a retry the sale may already be gone; the change is then only recorded.
+func finishSale(ctx context.Context, schedule *PriceSchedule, now time.Time) error {
+	_, err := productCollection.UpdateOne(ctx, bson.M{"_id": schedule.ProductID, "sale.schedule_id": schedule.ID}, bson.M{
+		"$unset": bson.M{"sale": ""},
+		"$set":   bson.M{"updated_at": now},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	product, err := findProduct(ctx, bson.M{"_id": schedule.ProductID})
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return recordPriceChange(ctx, product, "sale_ended", &schedule.ID)
+}
+
+// applyPriceSchedules ends the sales that are over, then starts what is
+// due, so a sale can hand over to the next one at the same moment.
+func applyPriceSchedules(ctx context.Context, now time.Time) (int, error) {
+	applied := 0
+	for _, step := range []func(context.Context, time.Time) error{endSale, startSchedule} {
+		for {
+			err := step(ctx, now)
+			if err == mongo.ErrNoDocuments {
+				break
+			}
+			if err != nil {
+				return applied, err
+			}
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+func runPriceScheduler(interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		applied, err := applyPriceSchedules(ctx, time.Now())
+		cancel()
+		if err != nil {
+			log.Printf("Failed to apply price schedules: %v", err)
+		} else if applied > 0 {
+			log.Printf("Applied %d price schedules", applied)
+		}
+	}
+}
+
+func toPriceScheduleResponse(s *PriceSchedule) *pb.PriceSchedule {
+	resp := &pb.PriceSchedule{
+		Id:        s.ID.Hex(),
+		ProductId: s.ProductID.Hex(),
+		Price:     s.Price,
+		StartsAt:  s.StartsAt.Format(time.RFC3339),
+		Status:    s.Status,
+		CreatedAt: s.CreatedAt.Format(time.RFC3339),
+	}
+	if s.EndsAt != nil {
+		resp.EndsAt = s.EndsAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func toPriceChangeResponse(e *PriceHistoryEntry) *pb.PriceChange {
+	resp := &pb.PriceChange{
+		ListPrice:      e.ListPrice,
+		EffectivePrice: e.EffectivePrice,
+		VariantPrices:  e.VariantPrices,
+		Reason:         e.Reason,
+		ChangedAt:      e.ChangedAt.Format(time.RFC3339),
+	}
+	if e.ScheduleID != nil {
+		resp.ScheduleId = e.ScheduleID.Hex()
+	}
+	return resp
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Product is a document in the products collection.
+type Product struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty"`
+	Name        string              `bson:"name"`
+	Description string              `bson:"description"`
+	Price       float64             `bson:"price"`
+	Category    string              `bson:"category"`
+	CategoryID  *primitive.ObjectID `bson:"category_id,omitempty"`
+	Stock       int32               `bson:"stock"`
+	Images      []string            `bson:"images"`
+	IsAvailable bool                `bson:"is_available"`
+	CreatedAt   time.Time           `bson:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at"`
+	Version     int64               `bson:"version"`
+	Variants    []Variant           `bson:"variants,omitempty"`
+
+	AvailabilityPolicy string `bson:"availability_policy,omitempty"`
+	ReorderThreshold   int32  `bson:"reorder_threshold,omitempty"`
+	Sale               *Sale  `bson:"sale,omitempty"`
+}
+
+// Variant is a purchasable version of a product, embedded in its document.
+// A nil Price means the product price applies.
+type Variant struct {
+	SKU     string            `bson:"sku"`
+	Options map[string]string `bson:"options,omitempty"`
+	Price   *float64          `bson:"price,omitempty"`
+	Stock   int32             `bson:"stock"`
+	Images  []string          `bson:"images,omitempty"`
+}
+
+const maxVariants = 100
+
+// toVariants validates the variants of a create or update request.
+func toVariants(in []*pb.ProductVariant) ([]Variant, error) {
+	if len(in) > maxVariants {
+		return nil, status.Errorf(codes.InvalidArgument, "A product can have at most %d variants", maxVariants)
+	}
+
+	seen := map[string]bool{}
+	var variants []Variant
+	for _, v := range in {
+		sku := strings.TrimSpace(v.Sku)
+		if sku == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "Every variant needs a SKU")
+		}
+		if seen[sku] {
+			return nil, status.Errorf(codes.InvalidArgument, "Duplicate SKU: %s", sku)
+		}
+		seen[sku] = true
+
+		for name, value := range v.Options {
+			if strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "Variant %s has an empty option", sku)
+			}
+		}
+		if v.Price != nil && *v.Price < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Variant %s has a negative price", sku)
+		}
+		if v.Stock < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Variant %s has negative stock", sku)
+		}
+
+		variants = append(variants, Variant{
+			SKU:     sku,
+			Options: v.Options,
+			Price:   v.Price,
+			Stock:   v.Stock,
+			Images:  v.Images,
+		})
+	}
+	return variants, nil
+}
+
+// duplicateSKUError maps a duplicate-key error on variants.sku to AlreadyExists.
+func duplicateSKUError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return status.Errorf(codes.AlreadyExists, "A variant SKU is already used by another product")
+	}
+	return nil
+}
+
+// productSortFields maps the sort_by values accepted by GetProducts to document fields.
+var productSortFields = map[string]string{
+	"":           "created_at",
+	"created_at": "created_at",
+	"price":      "price",
+	"name":       "name",
+}
+
+func (p *Product) sortValue(field string) interface{} {
+	switch field {
+	case "price":
+		return p.Price
+	case "name":
+		return p.Name
+	default:
+		return p.CreatedAt
+	}
+}
+
+func findProduct(ctx context.Context, filter bson.M) (*Product, error) {
+	raw, err := productCollection.FindOne(ctx, filter).Raw()
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "Product not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch product: %v", err)
+	}
+	return decodeProduct(raw)
+}
+
+// decodeProduct reports a stored document that does not fit Product as
+// DataLoss instead of skipping it.
+func decodeProduct(raw bson.Raw) (*Product, error) {
+	var product Product
+	if err := bson.Unmarshal(raw, &product); err != nil {
+		id := ""
+		if v, lookupErr := raw.LookupErr("_id"); lookupErr == nil {
+			if oid, ok := v.ObjectIDOK(); ok {
+				id = oid.Hex()
+			}
+		}
+		return nil, status.Errorf(codes.DataLoss, "Product %s has an invalid document: %v", id, err)
+	}
+	return &product, nil
+}
+
+// productCategory resolves the category a product is assigned to.
+func productCategory(ctx context.Context, id string) (*Category, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid category id: %v", err)
+	}
+	category, err := findCategory(ctx, oid)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.InvalidArgument, "Category %s does not exist", id)
+	}
+	return category, err
+}
+
+func productResponse(ctx context.Context, p *Product) (*pb.ProductResponse, error) {
+	resps, err := productResponses(ctx, []*Product{p})
+	if err != nil {
+		return nil, err
+	}
+	return resps[0], nil
+}
+
+// productResponses maps products to the API shape, resolving the breadcrumbs
+// of all their categories together.
+func productResponses(ctx context.Context, products []*Product) ([]*pb.ProductResponse, error) {
+	var categoryIDs []primitive.ObjectID
+	for _, p := range products {
+		if p.CategoryID != nil {
+			categoryIDs = append(categoryIDs, *p.CategoryID)
+		}
+	}
+	paths, err := categoryPaths(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var resps []*pb.ProductResponse
+	for _, p := range products {
+		resp := toProductResponse(p)
+		if p.CategoryID != nil {
+			resp.Breadcrumbs = paths[*p.CategoryID]
+		}
+		resps = append(resps, resp)
+	}
+	return resps, nil
+}
+
+func toProductResponse(p *Product) *pb.ProductResponse {
+	resp := &pb.ProductResponse{
+		Id:          p.ID.Hex(),
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Category:    p.Category,
+		Stock:       p.Stock,
+		Images:      p.Images,
+		IsAvailable: p.IsAvailable,
+		CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   p.UpdatedAt.Format(time.RFC3339),
+		Version:     p.Version,
+
+		AvailabilityPolicy: fromAvailabilityPolicy(p.AvailabilityPolicy),
+		ReorderThreshold:   p.ReorderThreshold,
+		ListPrice:          p.Price,
+		EffectivePrice:     p.effectivePrice(time.Now()),
+	}
+	if p.Sale != nil && p.Sale.EndsAt.After(time.Now()) {
+		resp.SaleEndsAt = p.Sale.EndsAt.Format(time.RFC3339)
+	}
+	if p.CategoryID != nil {
+		resp.CategoryId = p.CategoryID.Hex()
+	}
+	for _, v := range p.Variants {
+		resp.Variants = append(resp.Variants, &pb.ProductVariant{
+			Sku:     v.SKU,
+			Options: v.Options,
+			Price:   v.Price,
+			Stock:   v.Stock,
+			Images:  v.Images,
+		})
+	}
+	return resp
+}
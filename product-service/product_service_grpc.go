@@ -2,63 +2,89 @@ package main
 
 import (
 	"context"
-	pb "goFinalProject/proto/proto"
+	"log"
+	"maps"
+	"math"
+	"strings"
 	"time"
 
+	"goFinalProject/pagination"
+	pb "goFinalProject/proto/proto"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ProductServiceServer struct {
 	pb.UnimplementedProductServiceServer
 }
 
-func toStringSlice(a interface{}) []string {
-	arr, ok := a.(primitive.A)
-	if !ok {
-		return nil
-	}
-	var strSlice []string
-	for _, v := range arr {
-		if s, ok := v.(string); ok {
-			strSlice = append(strSlice, s)
-		}
-	}
-	return strSlice
-}
-
 func (s *ProductServiceServer) CreateProduct(ctx context.Context, req *pb.CreateProductRequest) (*pb.ProductResponse, error) {
-	now := time.Now()
-	product := bson.M{
-		"name":         req.Name,
-		"description":  req.Description,
-		"price":        req.Price,
-		"category":     req.Category,
-		"stock":        req.Stock,
-		"images":       req.Images,
-		"is_available": req.IsAvailable,
-		"created_at":   now,
-		"updated_at":   now,
+	if req.Category != "" && req.CategoryId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Products reference their category by category_id")
 	}
 
-	res, err := productCollection.InsertOne(ctx, product)
+	variants, err := toVariants(req.Variants)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := toAvailabilityPolicy(req.AvailabilityPolicy)
 	if err != nil {
 		return nil, err
 	}
+	if req.Price < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Price must not be negative")
+	}
+	if req.Stock < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Stock must not be negative")
+	}
+	if req.ReorderThreshold < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Reorder threshold must not be negative")
+	}
 
-	oid := res.InsertedID.(primitive.ObjectID)
-	return &pb.ProductResponse{
-		Id:          oid.Hex(),
+	now := time.Now()
+	product := &Product{
+		ID:          primitive.NewObjectID(),
 		Name:        req.Name,
 		Description: req.Description,
 		Price:       req.Price,
-		Category:    req.Category,
 		Stock:       req.Stock,
 		Images:      req.Images,
 		IsAvailable: req.IsAvailable,
-		CreatedAt:   now.Format(time.RFC3339),
-		UpdatedAt:   now.Format(time.RFC3339),
-	}, nil
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+		Variants:    variants,
+
+		AvailabilityPolicy: policy,
+		ReorderThreshold:   req.ReorderThreshold,
+	}
+	product.IsAvailable = product.availableFor(policy)
+	if req.CategoryId != "" {
+		category, err := productCategory(ctx, req.CategoryId)
+		if err != nil {
+			return nil, err
+		}
+		product.CategoryID = &category.ID
+		product.Category = category.Name
+	}
+
+	_, err = productCollection.InsertOne(ctx, product)
+	if dup := duplicateSKUError(err); dup != nil {
+		return nil, dup
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordPriceChange(ctx, product, "create", nil); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to record price change: %v", err)
+	}
+
+	return productResponse(ctx, product)
 }
 
 func (s *ProductServiceServer) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.ProductResponse, error) {
@@ -67,54 +93,222 @@ func (s *ProductServiceServer) GetProduct(ctx context.Context, req *pb.GetProduc
 		return nil, err
 	}
 
-	var product bson.M
-	err = productCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&product)
+	product, err := findProduct(ctx, bson.M{"_id": oid})
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.ProductResponse{
-		Id:          oid.Hex(),
-		Name:        product["name"].(string),
-		Description: product["description"].(string),
-		Price:       product["price"].(float64),
-		Category:    product["category"].(string),
-		Stock:       int32(product["stock"].(int32)),
-		Images:      toStringSlice(product["images"]),
-		IsAvailable: product["is_available"].(bool),
-		CreatedAt:   product["created_at"].(primitive.DateTime).Time().Format(time.RFC3339),
-		UpdatedAt:   product["updated_at"].(primitive.DateTime).Time().Format(time.RFC3339),
-	}, nil
+	return productResponse(ctx, product)
 }
 
 func (s *ProductServiceServer) GetProducts(ctx context.Context, req *pb.GetProductsRequest) (*pb.ProductsResponse, error) {
-	cursor, err := productCollection.Find(ctx, bson.M{})
+	sortBy, ok := productSortFields[req.SortBy]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Unsupported sort field: %s", req.SortBy)
+	}
+
+	filter, err := productsFilter(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+
+	after, err := pagination.After(req.PageToken, sortBy, req.Descending)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid page token")
+	}
+
+	total, err := productCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to count products: %v", err)
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	// Fetch one extra document to learn whether another page exists.
+	opts := options.Find().
+		SetSort(pagination.Sort(sortBy, req.Descending)).
+		SetLimit(pageSize + 1)
+
+	cursor, err := productCollection.Find(ctx, bson.M{"$and": bson.A{filter, after}}, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch products: %v", err)
+	}
 	defer cursor.Close(ctx)
 
-	var products []*pb.ProductResponse
+	var page []*Product
 	for cursor.Next(ctx) {
-		var product bson.M
-		if err := cursor.Decode(&product); err != nil {
-			continue
+		product, err := decodeProduct(cursor.Current)
+		if err != nil {
+			return nil, err
+		}
+		page = append(page, product)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch products: %v", err)
+	}
+
+	resp := &pb.ProductsResponse{TotalCount: total}
+	if int64(len(page)) > pageSize {
+		page = page[:pageSize]
+		last := page[len(page)-1]
+		resp.NextPageToken, err = pagination.EncodeToken(sortBy, last.sortValue(sortBy), last.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to encode page token: %v", err)
+		}
+	}
+	resp.Products, err = productResponses(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func productsFilter(ctx context.Context, req *pb.GetProductsRequest) (bson.M, error) {
+	filter := bson.M{}
+	if req.Category != "" {
+		filter["category"] = req.Category
+	}
+	if req.CategoryId != "" {
+		oid, err := primitive.ObjectIDFromHex(req.CategoryId)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid category id: %v", err)
+		}
+		ids, err := subtreeIDs(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+		filter["category_id"] = bson.M{"$in": ids}
+	}
+
+	if (req.MinPrice != nil && *req.MinPrice < 0) || (req.MaxPrice != nil && *req.MaxPrice < 0) {
+		return nil, status.Errorf(codes.InvalidArgument, "Price bounds must not be negative")
+	}
+	if req.MinPrice != nil && req.MaxPrice != nil && *req.MinPrice > *req.MaxPrice {
+		return nil, status.Errorf(codes.InvalidArgument, "min_price must not exceed max_price")
+	}
+	price := bson.M{}
+	if req.MinPrice != nil {
+		price["$gte"] = *req.MinPrice
+	}
+	if req.MaxPrice != nil {
+		price["$lte"] = *req.MaxPrice
+	}
+	if len(price) > 0 {
+		filter["price"] = price
+	}
+
+	if req.IsAvailable != nil {
+		filter["is_available"] = *req.IsAvailable
+	}
+	if req.InStock {
+		filter["$or"] = bson.A{
+			bson.M{"stock": bson.M{"$gt": 0}},
+			bson.M{"variants.stock": bson.M{"$gt": 0}},
+		}
+	}
+	return filter, nil
+}
+
+// SearchProducts ranks products by text score. Page tokens carry the score
+// of the last result, so pages stay stable while the catalog is unchanged.
+func (s *ProductServiceServer) SearchProducts(ctx context.Context, req *pb.SearchProductsRequest) (*pb.SearchProductsResponse, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Search query is required")
+	}
+
+	after, err := pagination.After(req.PageToken, "score", true)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid page token")
+	}
+
+	narrow := bson.M{}
+	if req.Category != "" {
+		narrow["category"] = req.Category
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$text": bson.M{"$search": req.Query}}}},
+		{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
+		{{Key: "$facet", Value: bson.M{
+			"results": bson.A{
+				bson.M{"$match": bson.M{"$and": bson.A{narrow, after}}},
+				bson.M{"$sort": pagination.Sort("score", true)},
+				// One extra document tells whether another page exists.
+				bson.M{"$limit": pageSize + 1},
+			},
+			"total": bson.A{
+				bson.M{"$match": narrow},
+				bson.M{"$count": "count"},
+			},
+			"categories": bson.A{
+				bson.M{"$group": bson.M{"_id": "$category", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			},
+		}}},
+	}
+
+	cursor, err := productCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to search products: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var out struct {
+		Results []bson.Raw `bson:"results"`
+		Total   []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Categories []struct {
+			Category string `bson:"_id"`
+			Count    int64  `bson:"count"`
+		} `bson:"categories"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&out); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode search results: %v", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to search products: %v", err)
+	}
+
+	resp := &pb.SearchProductsResponse{}
+	if len(out.Total) > 0 {
+		resp.TotalCount = out.Total[0].Count
+	}
+	for _, facet := range out.Categories {
+		resp.Categories = append(resp.Categories, &pb.CategoryFacet{Category: facet.Category, Count: facet.Count})
+	}
+
+	results := out.Results
+	if int64(len(results)) > pageSize {
+		results = results[:pageSize]
+		last, err := decodeProduct(results[len(results)-1])
+		if err != nil {
+			return nil, err
 		}
-		products = append(products, &pb.ProductResponse{
-			Id:          product["_id"].(primitive.ObjectID).Hex(),
-			Name:        product["name"].(string),
-			Description: product["description"].(string),
-			Price:       product["price"].(float64),
-			Category:    product["category"].(string),
-			Stock:       int32(product["stock"].(int32)),
-			Images:      toStringSlice(product["images"]),
-			IsAvailable: product["is_available"].(bool),
-			CreatedAt:   product["created_at"].(primitive.DateTime).Time().Format(time.RFC3339),
-			UpdatedAt:   product["updated_at"].(primitive.DateTime).Time().Format(time.RFC3339),
-		})
+		score, _ := results[len(results)-1].Lookup("score").DoubleOK()
+		resp.NextPageToken, err = pagination.EncodeToken("score", score, last.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to encode page token: %v", err)
+		}
+	}
+	var page []*Product
+	for _, raw := range results {
+		product, err := decodeProduct(raw)
+		if err != nil {
+			return nil, err
+		}
+		page = append(page, product)
+	}
+	resp.Products, err = productResponses(ctx, page)
+	if err != nil {
+		return nil, err
 	}
 
-	return &pb.ProductsResponse{Products: products}, nil
+	return resp, nil
 }
 
 func (s *ProductServiceServer) UpdateProduct(ctx context.Context, req *pb.UpdateProductRequest) (*pb.ProductResponse, error) {
@@ -123,35 +317,142 @@ func (s *ProductServiceServer) UpdateProduct(ctx context.Context, req *pb.Update
 		return nil, err
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"name":         req.Name,
-			"description":  req.Description,
-			"price":        req.Price,
-			"category":     req.Category,
-			"stock":        req.Stock,
-			"images":       req.Images,
-			"is_available": req.IsAvailable,
-			"updated_at":   time.Now(),
-		},
+	if req.Version <= 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "Version is required")
+	}
+
+	// Without a mask every unset field would be cleared, so one is required.
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "update_mask is required")
 	}
 
-	_, err = productCollection.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	// The current document tells which policy applies when the request does
+	// not change it, and what availability was before the update.
+	before, err := findProduct(ctx, bson.M{"_id": oid})
 	if err != nil {
 		return nil, err
 	}
+	if before.Version != req.Version {
+		return nil, status.Errorf(codes.Aborted, "Product was modified concurrently, current version is %d", before.Version)
+	}
+	policy := before.policy()
+	for _, path := range paths {
+		if path == "availability_policy" {
+			if policy, err = toAvailabilityPolicy(req.AvailabilityPolicy); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-	return &pb.ProductResponse{
-		Id:          req.Id,
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
-		Category:    req.Category,
-		Stock:       req.Stock,
-		Images:      req.Images,
-		IsAvailable: req.IsAvailable,
-		UpdatedAt:   time.Now().Format(time.RFC3339),
-	}, nil
+	set := bson.M{}
+	unset := bson.M{}
+	for _, path := range paths {
+		switch path {
+		case "name":
+			set["name"] = req.Name
+		case "description":
+			set["description"] = req.Description
+		case "price":
+			if req.Price < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "Price must not be negative")
+			}
+			set["price"] = req.Price
+		case "category_id":
+			if req.CategoryId == "" {
+				unset["category_id"] = ""
+				unset["category"] = ""
+				continue
+			}
+			category, err := productCategory(ctx, req.CategoryId)
+			if err != nil {
+				return nil, err
+			}
+			set["category_id"] = category.ID
+			set["category"] = category.Name
+		case "stock":
+			if req.Stock < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "Stock must not be negative")
+			}
+			set["stock"] = req.Stock
+		case "images":
+			set["images"] = req.Images
+		case "is_available":
+			// Other policies derive availability from stock.
+			if policy == availabilityManual {
+				set["is_available"] = req.IsAvailable
+			}
+		case "variants":
+			variants, err := toVariants(req.Variants)
+			if err != nil {
+				return nil, err
+			}
+			if len(variants) == 0 {
+				unset["variants"] = ""
+				continue
+			}
+			set["variants"] = variants
+		case "availability_policy":
+			set["availability_policy"] = policy
+		case "reorder_threshold":
+			if req.ReorderThreshold < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "Reorder threshold must not be negative")
+			}
+			set["reorder_threshold"] = req.ReorderThreshold
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown update_mask path: %s", path)
+		}
+	}
+	set["updated_at"] = time.Now()
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	raw, err := productCollection.FindOneAndUpdate(ctx, bson.M{"_id": oid, "version": req.Version}, update, opts).Raw()
+	if dup := duplicateSKUError(err); dup != nil {
+		return nil, dup
+	}
+	if err == mongo.ErrNoDocuments {
+		current, err := findProduct(ctx, bson.M{"_id": oid})
+		if err != nil {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Aborted, "Product was modified concurrently, current version is %d", current.Version)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to update product: %v", err)
+	}
+
+	product, err := decodeProduct(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	checkLowStock(ctx, before.totalStock(), product, "update")
+	if product.Price != before.Price || !maps.Equal(product.variantPrices(), before.variantPrices()) {
+		if err := recordPriceChange(ctx, product, "update", nil); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to record price change: %v", err)
+		}
+	}
+	if product.IsAvailable != before.IsAvailable {
+		if err := recordAvailabilityChange(ctx, oid, product.IsAvailable, "update"); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to record product event: %v", err)
+		}
+	}
+	if policy != availabilityManual {
+		changed, err := syncAvailability(ctx, oid, "update")
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to update availability: %v", err)
+		}
+		if changed {
+			if product, err = findProduct(ctx, bson.M{"_id": oid}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return productResponse(ctx, product)
 }
 
 func (s *ProductServiceServer) DeleteProduct(ctx context.Context, req *pb.DeleteProductRequest) (*pb.DeleteProductResponse, error) {
@@ -170,3 +471,282 @@ func (s *ProductServiceServer) DeleteProduct(ctx context.Context, req *pb.Delete
 		Success: true,
 	}, nil
 }
+
+// ReserveStock takes stock for every line. If any line cannot be served, the
+// lines already taken are put back and nothing stays reserved.
+func (s *ProductServiceServer) ReserveStock(ctx context.Context, req *pb.ReserveStockRequest) (*pb.Reservation, error) {
+	if req.UserId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "User id is required")
+	}
+	lines, err := toStockLines(req.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, line := range lines {
+		if err := takeStock(ctx, line); err != nil {
+			rollBackStock(ctx, lines[:i])
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	reservation := &Reservation{
+		ID:        primitive.NewObjectID(),
+		UserID:    req.UserId,
+		Lines:     lines,
+		Status:    reservationPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(reservationTTL),
+	}
+	if _, err := reservationCollection.InsertOne(ctx, reservation); err != nil {
+		rollBackStock(ctx, lines)
+		return nil, status.Errorf(codes.Internal, "Failed to store reservation: %v", err)
+	}
+
+	return toReservationResponse(reservation), nil
+}
+
+// rollBackStock returns the stock of a reservation that was not made. No
+// reservation exists for the sweeper to release, so every line is tried and
+// each one that fails is logged for an operator to correct.
+func rollBackStock(ctx context.Context, lines []StockLine) {
+	for _, line := range lines {
+		if err := returnStock(ctx, line, "rolled_back"); err != nil {
+			log.Printf("Failed to return %d of product %s %s after a failed reservation: %v",
+				line.Quantity, line.ProductID.Hex(), line.VariantSKU, err)
+		}
+	}
+}
+
+func (s *ProductServiceServer) CommitReservation(ctx context.Context, req *pb.CommitReservationRequest) (*pb.Reservation, error) {
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Order id is required")
+	}
+	lines, err := toStockLines(req.Lines)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := reservationFilter(req.Id, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The order must be for exactly the stock that was held; the lines never
+	// change once reserved, so checking them before the commit is enough.
+	var reservation Reservation
+	err = reservationCollection.FindOne(ctx, filter).Decode(&reservation)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.FailedPrecondition, "No pending reservation with this id")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch reservation: %v", err)
+	}
+	if !sameStockLines(reservation.Lines, lines) {
+		return nil, status.Errorf(codes.FailedPrecondition, "Order lines do not match the reservation")
+	}
+
+	return finishReservation(ctx, filter, reservationCommitted, bson.M{"order_id": req.OrderId})
+}
+
+func (s *ProductServiceServer) ReleaseReservation(ctx context.Context, req *pb.ReleaseReservationRequest) (*pb.Reservation, error) {
+	filter, err := reservationFilter(req.Id, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return finishReservation(ctx, filter, reservationReleased, nil)
+}
+
+// reservationFilter matches the pending reservation with this id if it was
+// made for the given user.
+func reservationFilter(id, userID string) (bson.M, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	if userID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "User id is required")
+	}
+	return bson.M{
+		"_id":        oid,
+		"user_id":    userID,
+		"status":     reservationPending,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, nil
+}
+
+func finishReservation(ctx context.Context, filter bson.M, to string, set bson.M) (*pb.Reservation, error) {
+	reservation, err := endReservation(ctx, filter, to, set)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.FailedPrecondition, "No pending reservation with this id")
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "Failed to update reservation: %v", err)
+	}
+
+	return toReservationResponse(reservation), nil
+}
+
+// GetLowStockReport lists the products at or below their reorder threshold
+// with how fast they sold over the window, soonest to run out first.
+func (s *ProductServiceServer) GetLowStockReport(ctx context.Context, req *pb.LowStockReportRequest) (*pb.LowStockReport, error) {
+	windowDays := req.WindowDays
+	if windowDays == 0 {
+		windowDays = defaultVelocityWindowDays
+	}
+	if windowDays < 0 || windowDays > maxVelocityWindowDays {
+		return nil, status.Errorf(codes.InvalidArgument, "Window must be between 1 and %d days", maxVelocityWindowDays)
+	}
+
+	cursor, err := productCollection.Find(ctx, bson.M{
+		"reorder_threshold": bson.M{"$gt": 0},
+		"$expr":             bson.M{"$lte": bson.A{totalStockExpr, "$reorder_threshold"}},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch products: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var products []*Product
+	var ids []string
+	for cursor.Next(ctx) {
+		product, err := decodeProduct(cursor.Current)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+		ids = append(ids, product.ID.Hex())
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch products: %v", err)
+	}
+
+	sold, err := unitsSold(ctx, ids, time.Now().AddDate(0, 0, -int(windowDays)))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.LowStockReport{WindowDays: windowDays}
+	for _, product := range products {
+		item := &pb.LowStockItem{
+			ProductId:        product.ID.Hex(),
+			Name:             product.Name,
+			Stock:            product.totalStock(),
+			ReorderThreshold: product.ReorderThreshold,
+			UnitsSold:        sold[product.ID.Hex()],
+		}
+		item.DailyVelocity = float64(item.UnitsSold) / float64(windowDays)
+		if item.DailyVelocity > 0 {
+			cover := math.Max(float64(item.Stock), 0) / item.DailyVelocity
+			item.DaysOfCover = &cover
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	sortLowStockItems(resp.Items)
+
+	return resp, nil
+}
+
+// SchedulePriceChange queues a future list price change, or a sale when an
+// end is given. The price scheduler applies it once it is due.
+func (s *ProductServiceServer) SchedulePriceChange(ctx context.Context, req *pb.SchedulePriceChangeRequest) (*pb.PriceSchedule, error) {
+	oid, err := primitive.ObjectIDFromHex(req.ProductId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	if req.Price < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Price must not be negative")
+	}
+
+	now := time.Now()
+	startsAt, err := time.Parse(time.RFC3339, req.StartsAt)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid starts_at: %v", err)
+	}
+	if !startsAt.After(now) {
+		return nil, status.Errorf(codes.InvalidArgument, "starts_at must be in the future")
+	}
+	schedule := &PriceSchedule{
+		ID:        primitive.NewObjectID(),
+		ProductID: oid,
+		Price:     req.Price,
+		StartsAt:  startsAt,
+		Status:    schedulePending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.EndsAt != "" {
+		endsAt, err := time.Parse(time.RFC3339, req.EndsAt)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid ends_at: %v", err)
+		}
+		if !endsAt.After(startsAt) {
+			return nil, status.Errorf(codes.InvalidArgument, "ends_at must be after starts_at")
+		}
+		schedule.EndsAt = &endsAt
+	}
+
+	if _, err := findProduct(ctx, bson.M{"_id": oid}); err != nil {
+		return nil, err
+	}
+
+	if _, err := priceScheduleCollection.InsertOne(ctx, schedule); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to schedule price change: %v", err)
+	}
+
+	return toPriceScheduleResponse(schedule), nil
+}
+
+func (s *ProductServiceServer) GetPriceHistory(ctx context.Context, req *pb.GetPriceHistoryRequest) (*pb.PriceHistoryResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.ProductId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	after, err := pagination.After(req.PageToken, "changed_at", true)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid page token")
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	opts := options.Find().
+		SetSort(pagination.Sort("changed_at", true)).
+		SetLimit(pageSize + 1)
+
+	cursor, err := priceHistoryCollection.Find(ctx, bson.M{"$and": bson.A{bson.M{"product_id": oid}, after}}, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch price history: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var page []*PriceHistoryEntry
+	for cursor.Next(ctx) {
+		var entry PriceHistoryEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode price history: %v", err)
+		}
+		page = append(page, &entry)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch price history: %v", err)
+	}
+
+	resp := &pb.PriceHistoryResponse{}
+	if int64(len(page)) > pageSize {
+		page = page[:pageSize]
+		last := page[len(page)-1]
+		resp.NextPageToken, err = pagination.EncodeToken("changed_at", last.ChangedAt, last.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to encode page token: %v", err)
+		}
+	}
+	for _, entry := range page {
+		resp.Changes = append(resp.Changes, toPriceChangeResponse(entry))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	reservationTTL           = 15 * time.Minute
+	reservationSweepInterval = time.Minute
+
+	reservationPending   = "pending"
+	reservationCommitted = "committed"
+	reservationReleased  = "released"
+	reservationExpired   = "expired"
+)
+
+// Reservation is a document in the reservations collection. Its stock is
+// taken off the products when it is made; committing keeps it off, while
+// releasing or expiring puts it back.
+type Reservation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    string             `bson:"user_id"`
+	Lines     []StockLine        `bson:"lines"`
+	Status    string             `bson:"status"`
+	OrderID   string             `bson:"order_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
+
+type StockLine struct {
+	ProductID  primitive.ObjectID `bson:"product_id"`
+	VariantSKU string             `bson:"variant_sku,omitempty"`
+	Quantity   int32              `bson:"quantity"`
+}
+
+func toStockLines(in []*pb.StockLine) ([]StockLine, error) {
+	if len(in) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "At least one line is required")
+	}
+
+	var lines []StockLine
+	for _, line := range in {
+		oid, err := primitive.ObjectIDFromHex(line.ProductId)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid product id: %v", err)
+		}
+		if line.Quantity <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Quantity must be positive")
+		}
+		lines = append(lines, StockLine{ProductID: oid, VariantSKU: line.VariantSku, Quantity: line.Quantity})
+	}
+	return lines, nil
+}
+
+// sameStockLines reports whether a and b hold the same quantities of the
+// same products and variants, in any order.
+func sameStockLines(a, b []StockLine) bool {
+	type key struct {
+		productID  primitive.ObjectID
+		variantSKU string
+	}
+	counts := map[key]int32{}
+	for _, line := range a {
+		counts[key{line.ProductID, line.VariantSKU}] += line.Quantity
+	}
+	for _, line := range b {
+		counts[key{line.ProductID, line.VariantSKU}] -= line.Quantity
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// takeStock decrements the stock of one line only if enough is left, so
+// stock never goes negative, except on backorder products, which take
+// orders past zero.
+func takeStock(ctx context.Context, line StockLine) error {
+	backorder := bson.M{"availability_policy": availabilityBackorder}
+	filter := bson.M{"_id": line.ProductID, "$or": bson.A{
+		bson.M{"stock": bson.M{"$gte": line.Quantity}},
+		backorder,
+	}}
+	field := "stock"
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if line.VariantSKU != "" {
+		backorder["variants.sku"] = line.VariantSKU
+		filter["$or"] = bson.A{
+			bson.M{"variants": bson.M{"$elemMatch": bson.M{"sku": line.VariantSKU, "stock": bson.M{"$gte": line.Quantity}}}},
+			backorder,
+		}
+		field = "variants.$[v].stock"
+		opts.SetArrayFilters(options.ArrayFilters{Filters: bson.A{bson.M{"v.sku": line.VariantSKU}}})
+	}
+
+	raw, err := productCollection.FindOneAndUpdate(ctx, filter, bson.M{
+		"$inc": bson.M{field: -line.Quantity, "version": 1},
+	}, opts).Raw()
+	if err == mongo.ErrNoDocuments {
+		return status.Errorf(codes.FailedPrecondition, "Insufficient stock for product %s %s", line.ProductID.Hex(), line.VariantSKU)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to reserve stock: %v", err)
+	}
+
+	syncAvailabilityAfterStock(ctx, line.ProductID, "reserved")
+	if product, err := decodeProduct(raw); err == nil {
+		checkLowStock(ctx, product.totalStock()+line.Quantity, product, "reserved")
+	}
+	return nil
+}
+
+func returnStock(ctx context.Context, line StockLine, reason string) error {
+	filter := bson.M{"_id": line.ProductID}
+	field := "stock"
+	if line.VariantSKU != "" {
+		filter["variants.sku"] = line.VariantSKU
+		field = "variants.$.stock"
+	}
+
+	_, err := productCollection.UpdateOne(ctx, filter, bson.M{
+		"$inc": bson.M{field: line.Quantity, "version": 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	syncAvailabilityAfterStock(ctx, line.ProductID, reason)
+	return nil
+}
+
+// endReservation moves a pending reservation to a final status. Only the
+// caller that wins the transition returns the stock, so it is never
+// returned twice.
+func endReservation(ctx context.Context, filter bson.M, to string, set bson.M) (*Reservation, error) {
+	filter["status"] = reservationPending
+	if set == nil {
+		set = bson.M{}
+	}
+	set["status"] = to
+	set["updated_at"] = time.Now()
+
+	var reservation Reservation
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := reservationCollection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == reservationReleased || to == reservationExpired {
+		for _, line := range reservation.Lines {
+			if err := returnStock(ctx, line, to); err != nil {
+				return nil, status.Errorf(codes.Internal, "Failed to return stock: %v", err)
+			}
+		}
+	}
+	return &reservation, nil
+}
+
+// releaseExpiredReservations puts the stock of abandoned reservations back.
+func releaseExpiredReservations(ctx context.Context, now time.Time) (int, error) {
+	released := 0
+	for {
+		_, err := endReservation(ctx, bson.M{"expires_at": bson.M{"$lte": now}}, reservationExpired, nil)
+		if err == mongo.ErrNoDocuments {
+			return released, nil
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+	}
+}
+
+func sweepReservations(interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		released, err := releaseExpiredReservations(ctx, time.Now())
+		cancel()
+		if err != nil {
+			log.Printf("Failed to release expired reservations: %v", err)
+		} else if released > 0 {
+			log.Printf("Released %d expired reservations", released)
+		}
+	}
+}
+
+func toReservationResponse(r *Reservation) *pb.Reservation {
+	resp := &pb.Reservation{
+		Id:        r.ID.Hex(),
+		Status:    r.Status,
+		ExpiresAt: r.ExpiresAt.Format(time.RFC3339),
+		OrderId:   r.OrderID,
+	}
+	for _, line := range r.Lines {
+		resp.Lines = append(resp.Lines, &pb.StockLine{
+			ProductId:  line.ProductID.Hex(),
+			VariantSku: line.VariantSKU,
+			Quantity:   line.Quantity,
+		})
+	}
+	return resp
+}
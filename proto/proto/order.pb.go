@@ -22,9 +22,14 @@ const (
 )
 
 type ProductItem struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ProductId     string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
-	Quantity      int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductId string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity  int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Required for products that have variants.
+	VariantSku string `protobuf:"bytes,3,opt,name=variant_sku,json=variantSku,proto3" json:"variant_sku,omitempty"`
+	// Price of one unit when the order was placed. Set by order-service from
+	// the catalog; ignored in requests.
+	UnitPrice     float64 `protobuf:"fixed64,4,opt,name=unit_price,json=unitPrice,proto3" json:"unit_price,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -73,13 +78,28 @@ func (x *ProductItem) GetQuantity() int32 {
 	return 0
 }
 
+func (x *ProductItem) GetVariantSku() string {
+	if x != nil {
+		return x.VariantSku
+	}
+	return ""
+}
+
+func (x *ProductItem) GetUnitPrice() float64 {
+	if x != nil {
+		return x.UnitPrice
+	}
+	return 0
+}
+
 type CreateOrderRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Products      []*ProductItem         `protobuf:"bytes,2,rep,name=products,proto3" json:"products,omitempty"`
-	TotalPrice    float64                `protobuf:"fixed64,3,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserId   string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Products []*ProductItem         `protobuf:"bytes,2,rep,name=products,proto3" json:"products,omitempty"`
+	// Address from the user's address book to ship to; the order keeps a copy.
+	ShippingAddressId string `protobuf:"bytes,6,opt,name=shipping_address_id,json=shippingAddressId,proto3" json:"shipping_address_id,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *CreateOrderRequest) Reset() {
@@ -126,11 +146,11 @@ func (x *CreateOrderRequest) GetProducts() []*ProductItem {
 	return nil
 }
 
-func (x *CreateOrderRequest) GetTotalPrice() float64 {
+func (x *CreateOrderRequest) GetShippingAddressId() string {
 	if x != nil {
-		return x.TotalPrice
+		return x.ShippingAddressId
 	}
-	return 0
+	return ""
 }
 
 type GetOrderRequest struct {
@@ -258,13 +278,14 @@ func (x *DeleteOrderRequest) GetId() string {
 }
 
 type OrderResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Products      []*ProductItem         `protobuf:"bytes,3,rep,name=products,proto3" json:"products,omitempty"`
-	TotalPrice    float64                `protobuf:"fixed64,4,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId          string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Products        []*ProductItem         `protobuf:"bytes,3,rep,name=products,proto3" json:"products,omitempty"`
+	TotalPrice      float64                `protobuf:"fixed64,4,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
+	ShippingAddress *Address               `protobuf:"bytes,5,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *OrderResponse) Reset() {
@@ -325,6 +346,13 @@ func (x *OrderResponse) GetTotalPrice() float64 {
 	return 0
 }
 
+func (x *OrderResponse) GetShippingAddress() *Address {
+	if x != nil {
+		return x.ShippingAddress
+	}
+	return nil
+}
+
 type OrdersResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Orders        []*OrderResponse       `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
@@ -421,41 +449,186 @@ func (x *DeleteOrderResponse) GetSuccess() bool {
 	return false
 }
 
+type GetUserOrdersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserOrdersRequest) Reset() {
+	*x = GetUserOrdersRequest{}
+	mi := &file_order_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserOrdersRequest) ProtoMessage() {}
+
+func (x *GetUserOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserOrdersRequest.ProtoReflect.Descriptor instead.
+func (*GetUserOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetUserOrdersRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type AnonymizeUserOrdersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnonymizeUserOrdersRequest) Reset() {
+	*x = AnonymizeUserOrdersRequest{}
+	mi := &file_order_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnonymizeUserOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnonymizeUserOrdersRequest) ProtoMessage() {}
+
+func (x *AnonymizeUserOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnonymizeUserOrdersRequest.ProtoReflect.Descriptor instead.
+func (*AnonymizeUserOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AnonymizeUserOrdersRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type AnonymizeUserOrdersResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	AnonymizedOrders int64                  `protobuf:"varint,1,opt,name=anonymized_orders,json=anonymizedOrders,proto3" json:"anonymized_orders,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *AnonymizeUserOrdersResponse) Reset() {
+	*x = AnonymizeUserOrdersResponse{}
+	mi := &file_order_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnonymizeUserOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnonymizeUserOrdersResponse) ProtoMessage() {}
+
+func (x *AnonymizeUserOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_order_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnonymizeUserOrdersResponse.ProtoReflect.Descriptor instead.
+func (*AnonymizeUserOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_order_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AnonymizeUserOrdersResponse) GetAnonymizedOrders() int64 {
+	if x != nil {
+		return x.AnonymizedOrders
+	}
+	return 0
+}
+
 var File_order_proto protoreflect.FileDescriptor
 
 const file_order_proto_rawDesc = "" +
 	"\n" +
-	"\vorder.proto\x12\x05proto\"H\n" +
+	"\vorder.proto\x12\x05proto\x1a\n" +
+	"user.proto\"\x88\x01\n" +
 	"\vProductItem\x12\x1d\n" +
 	"\n" +
 	"product_id\x18\x01 \x01(\tR\tproductId\x12\x1a\n" +
-	"\bquantity\x18\x02 \x01(\x05R\bquantity\"~\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity\x12\x1f\n" +
+	"\vvariant_sku\x18\x03 \x01(\tR\n" +
+	"variantSku\x12\x1d\n" +
+	"\n" +
+	"unit_price\x18\x04 \x01(\x01R\tunitPrice\"\xce\x01\n" +
 	"\x12CreateOrderRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12.\n" +
-	"\bproducts\x18\x02 \x03(\v2\x12.proto.ProductItemR\bproducts\x12\x1f\n" +
-	"\vtotal_price\x18\x03 \x01(\x01R\n" +
-	"totalPrice\"!\n" +
+	"\bproducts\x18\x02 \x03(\v2\x12.proto.ProductItemR\bproducts\x12.\n" +
+	"\x13shipping_address_id\x18\x06 \x01(\tR\x11shippingAddressIdJ\x04\b\x03\x10\x04J\x04\b\x04\x10\x05J\x04\b\x05\x10\x06R\vtotal_priceR\x10shipping_addressR\x0ereservation_id\"!\n" +
 	"\x0fGetOrderRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"\x12\n" +
 	"\x10GetOrdersRequest\"$\n" +
 	"\x12DeleteOrderRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"\x89\x01\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xc4\x01\n" +
 	"\rOrderResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
 	"\auser_id\x18\x02 \x01(\tR\x06userId\x12.\n" +
 	"\bproducts\x18\x03 \x03(\v2\x12.proto.ProductItemR\bproducts\x12\x1f\n" +
 	"\vtotal_price\x18\x04 \x01(\x01R\n" +
-	"totalPrice\">\n" +
+	"totalPrice\x129\n" +
+	"\x10shipping_address\x18\x05 \x01(\v2\x0e.proto.AddressR\x0fshippingAddress\">\n" +
 	"\x0eOrdersResponse\x12,\n" +
 	"\x06orders\x18\x01 \x03(\v2\x14.proto.OrderResponseR\x06orders\"?\n" +
 	"\x13DeleteOrderResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
-	"\asuccess\x18\x02 \x01(\bR\asuccess2\x8b\x02\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\"/\n" +
+	"\x14GetUserOrdersRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"5\n" +
+	"\x1aAnonymizeUserOrdersRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"J\n" +
+	"\x1bAnonymizeUserOrdersResponse\x12+\n" +
+	"\x11anonymized_orders\x18\x01 \x01(\x03R\x10anonymizedOrders2\xae\x03\n" +
 	"\fOrderService\x12>\n" +
 	"\vCreateOrder\x12\x19.proto.CreateOrderRequest\x1a\x14.proto.OrderResponse\x128\n" +
 	"\bGetOrder\x12\x16.proto.GetOrderRequest\x1a\x14.proto.OrderResponse\x12;\n" +
 	"\tGetOrders\x12\x17.proto.GetOrdersRequest\x1a\x15.proto.OrdersResponse\x12D\n" +
-	"\vDeleteOrder\x12\x19.proto.DeleteOrderRequest\x1a\x1a.proto.DeleteOrderResponseB\tZ\a./protob\x06proto3"
+	"\vDeleteOrder\x12\x19.proto.DeleteOrderRequest\x1a\x1a.proto.DeleteOrderResponse\x12C\n" +
+	"\rGetUserOrders\x12\x1b.proto.GetUserOrdersRequest\x1a\x15.proto.OrdersResponse\x12\\\n" +
+	"\x13AnonymizeUserOrders\x12!.proto.AnonymizeUserOrdersRequest\x1a\".proto.AnonymizeUserOrdersResponseB\tZ\a./protob\x06proto3"
 
 var (
 	file_order_proto_rawDescOnce sync.Once
@@ -469,34 +642,43 @@ func file_order_proto_rawDescGZIP() []byte {
 	return file_order_proto_rawDescData
 }
 
-var file_order_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_order_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_order_proto_goTypes = []any{
-	(*ProductItem)(nil),         // 0: proto.ProductItem
-	(*CreateOrderRequest)(nil),  // 1: proto.CreateOrderRequest
-	(*GetOrderRequest)(nil),     // 2: proto.GetOrderRequest
-	(*GetOrdersRequest)(nil),    // 3: proto.GetOrdersRequest
-	(*DeleteOrderRequest)(nil),  // 4: proto.DeleteOrderRequest
-	(*OrderResponse)(nil),       // 5: proto.OrderResponse
-	(*OrdersResponse)(nil),      // 6: proto.OrdersResponse
-	(*DeleteOrderResponse)(nil), // 7: proto.DeleteOrderResponse
+	(*ProductItem)(nil),                 // 0: proto.ProductItem
+	(*CreateOrderRequest)(nil),          // 1: proto.CreateOrderRequest
+	(*GetOrderRequest)(nil),             // 2: proto.GetOrderRequest
+	(*GetOrdersRequest)(nil),            // 3: proto.GetOrdersRequest
+	(*DeleteOrderRequest)(nil),          // 4: proto.DeleteOrderRequest
+	(*OrderResponse)(nil),               // 5: proto.OrderResponse
+	(*OrdersResponse)(nil),              // 6: proto.OrdersResponse
+	(*DeleteOrderResponse)(nil),         // 7: proto.DeleteOrderResponse
+	(*GetUserOrdersRequest)(nil),        // 8: proto.GetUserOrdersRequest
+	(*AnonymizeUserOrdersRequest)(nil),  // 9: proto.AnonymizeUserOrdersRequest
+	(*AnonymizeUserOrdersResponse)(nil), // 10: proto.AnonymizeUserOrdersResponse
+	(*Address)(nil),                     // 11: proto.Address
 }
 var file_order_proto_depIdxs = []int32{
-	0, // 0: proto.CreateOrderRequest.products:type_name -> proto.ProductItem
-	0, // 1: proto.OrderResponse.products:type_name -> proto.ProductItem
-	5, // 2: proto.OrdersResponse.orders:type_name -> proto.OrderResponse
-	1, // 3: proto.OrderService.CreateOrder:input_type -> proto.CreateOrderRequest
-	2, // 4: proto.OrderService.GetOrder:input_type -> proto.GetOrderRequest
-	3, // 5: proto.OrderService.GetOrders:input_type -> proto.GetOrdersRequest
-	4, // 6: proto.OrderService.DeleteOrder:input_type -> proto.DeleteOrderRequest
-	5, // 7: proto.OrderService.CreateOrder:output_type -> proto.OrderResponse
-	5, // 8: proto.OrderService.GetOrder:output_type -> proto.OrderResponse
-	6, // 9: proto.OrderService.GetOrders:output_type -> proto.OrdersResponse
-	7, // 10: proto.OrderService.DeleteOrder:output_type -> proto.DeleteOrderResponse
-	7, // [7:11] is the sub-list for method output_type
-	3, // [3:7] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	0,  // 0: proto.CreateOrderRequest.products:type_name -> proto.ProductItem
+	0,  // 1: proto.OrderResponse.products:type_name -> proto.ProductItem
+	11, // 2: proto.OrderResponse.shipping_address:type_name -> proto.Address
+	5,  // 3: proto.OrdersResponse.orders:type_name -> proto.OrderResponse
+	1,  // 4: proto.OrderService.CreateOrder:input_type -> proto.CreateOrderRequest
+	2,  // 5: proto.OrderService.GetOrder:input_type -> proto.GetOrderRequest
+	3,  // 6: proto.OrderService.GetOrders:input_type -> proto.GetOrdersRequest
+	4,  // 7: proto.OrderService.DeleteOrder:input_type -> proto.DeleteOrderRequest
+	8,  // 8: proto.OrderService.GetUserOrders:input_type -> proto.GetUserOrdersRequest
+	9,  // 9: proto.OrderService.AnonymizeUserOrders:input_type -> proto.AnonymizeUserOrdersRequest
+	5,  // 10: proto.OrderService.CreateOrder:output_type -> proto.OrderResponse
+	5,  // 11: proto.OrderService.GetOrder:output_type -> proto.OrderResponse
+	6,  // 12: proto.OrderService.GetOrders:output_type -> proto.OrdersResponse
+	7,  // 13: proto.OrderService.DeleteOrder:output_type -> proto.DeleteOrderResponse
+	6,  // 14: proto.OrderService.GetUserOrders:output_type -> proto.OrdersResponse
+	10, // 15: proto.OrderService.AnonymizeUserOrders:output_type -> proto.AnonymizeUserOrdersResponse
+	10, // [10:16] is the sub-list for method output_type
+	4,  // [4:10] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_order_proto_init() }
@@ -504,13 +686,14 @@ func file_order_proto_init() {
 	if File_order_proto != nil {
 		return
 	}
+	file_user_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_order_proto_rawDesc), len(file_order_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
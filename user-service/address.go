@@ -0,0 +1,120 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxAddresses = 20
+
+// Address is an entry in a user's address book, embedded in the user document.
+type Address struct {
+	ID              primitive.ObjectID `bson:"id"`
+	Label           string             `bson:"label,omitempty"`
+	Recipient       string             `bson:"recipient"`
+	Line1           string             `bson:"line1"`
+	Line2           string             `bson:"line2,omitempty"`
+	City            string             `bson:"city"`
+	Region          string             `bson:"region,omitempty"`
+	PostalCode      string             `bson:"postalCode,omitempty"`
+	Country         string             `bson:"country"`
+	Phone           string             `bson:"phone,omitempty"`
+	DefaultShipping bool               `bson:"defaultShipping"`
+	DefaultBilling  bool               `bson:"defaultBilling"`
+}
+
+type countryRule struct {
+	postalCode     *regexp.Regexp
+	regionRequired bool
+}
+
+// countryRules holds the countries we validate beyond the common fields.
+// Other countries only need a well-formed country code.
+var countryRules = map[string]countryRule{
+	"US": {postalCode: regexp.MustCompile(`^\d{5}(-\d{4})?$`), regionRequired: true},
+	"CA": {postalCode: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`), regionRequired: true},
+	"AU": {postalCode: regexp.MustCompile(`^\d{4}$`), regionRequired: true},
+	"GB": {postalCode: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`)},
+	"DE": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"FR": {postalCode: regexp.MustCompile(`^\d{5}$`)},
+	"JP": {postalCode: regexp.MustCompile(`^\d{3}-?\d{4}$`)},
+	"RU": {postalCode: regexp.MustCompile(`^\d{6}$`)},
+	"KZ": {postalCode: regexp.MustCompile(`^\d{6}$`)},
+	"UZ": {postalCode: regexp.MustCompile(`^\d{6}$`)},
+}
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+func toAddress(in *pb.Address) Address {
+	return Address{
+		Label:           strings.TrimSpace(in.Label),
+		Recipient:       strings.TrimSpace(in.Recipient),
+		Line1:           strings.TrimSpace(in.Line1),
+		Line2:           strings.TrimSpace(in.Line2),
+		City:            strings.TrimSpace(in.City),
+		Region:          strings.TrimSpace(in.Region),
+		PostalCode:      strings.ToUpper(strings.TrimSpace(in.PostalCode)),
+		Country:         strings.ToUpper(strings.TrimSpace(in.Country)),
+		Phone:           strings.TrimSpace(in.Phone),
+		DefaultShipping: in.DefaultShipping,
+		DefaultBilling:  in.DefaultBilling,
+	}
+}
+
+func (a *Address) validate() error {
+	var missing []string
+	if a.Recipient == "" {
+		missing = append(missing, "recipient")
+	}
+	if a.Line1 == "" {
+		missing = append(missing, "line1")
+	}
+	if a.City == "" {
+		missing = append(missing, "city")
+	}
+	if a.Country == "" {
+		missing = append(missing, "country")
+	}
+	if len(missing) > 0 {
+		return status.Errorf(codes.InvalidArgument, "Address is missing %s", strings.Join(missing, ", "))
+	}
+
+	if !countryCode.MatchString(a.Country) {
+		return status.Errorf(codes.InvalidArgument, "Country must be an ISO 3166-1 alpha-2 code")
+	}
+
+	rule, ok := countryRules[a.Country]
+	if !ok {
+		return nil
+	}
+	if rule.regionRequired && a.Region == "" {
+		return status.Errorf(codes.InvalidArgument, "Region is required for %s addresses", a.Country)
+	}
+	if !rule.postalCode.MatchString(a.PostalCode) {
+		return status.Errorf(codes.InvalidArgument, "Invalid postal code for %s", a.Country)
+	}
+	return nil
+}
+
+func toAddressResponse(a Address) *pb.Address {
+	return &pb.Address{
+		Id:              a.ID.Hex(),
+		Label:           a.Label,
+		Recipient:       a.Recipient,
+		Line1:           a.Line1,
+		Line2:           a.Line2,
+		City:            a.City,
+		Region:          a.Region,
+		PostalCode:      a.PostalCode,
+		Country:         a.Country,
+		Phone:           a.Phone,
+		DefaultShipping: a.DefaultShipping,
+		DefaultBilling:  a.DefaultBilling,
+	}
+}
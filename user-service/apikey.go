@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"goFinalProject/auth"
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	apiKeyPrefix        = "mk_"
+	apiKeyDisplayLength = 8
+	maxAPIKeyName       = 100
+)
+
+// APIKey is a document in the api_keys collection. Only the hash of the key
+// is stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	Name       string             `bson:"name"`
+	KeyHash    string             `bson:"key_hash"`
+	Prefix     string             `bson:"prefix"`
+	Scopes     []string           `bson:"scopes"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty"`
+}
+
+// canHoldAPIKeys reports whether a user with the given role may own API keys.
+func canHoldAPIKeys(role string) bool {
+	return role == auth.RoleAdmin || role == auth.RoleIntegration
+}
+
+func validScope(scope string) bool {
+	for _, known := range auth.KnownScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// newAPIKey returns a fresh key and the hash stored in its place.
+func newAPIKey() (string, string, error) {
+	token, _, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	key := apiKeyPrefix + token
+	return key, hashToken(key), nil
+}
+
+// validateAPIKey is the interceptor's KeyValidator for user-service. Other
+// services reach the same check through AuthenticateAPIKey.
+func validateAPIKey(ctx context.Context, key string) (*auth.Claims, error) {
+	apiKey, err := lookupAPIKey(ctx, key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		APIKeyID: apiKey.ID.Hex(),
+		Scopes:   apiKey.Scopes,
+	}
+	claims.Subject = apiKey.UserID.Hex()
+	return claims, nil
+}
+
+// lookupAPIKey finds a usable key and records that it was used. Keys stop
+// working when their owner is deleted or no longer holds a key-holding role.
+func lookupAPIKey(ctx context.Context, key string, now time.Time) (*APIKey, error) {
+	var apiKey APIKey
+	err := apiKeyCollection.FindOne(ctx, bson.M{
+		"key_hash":   hashToken(key),
+		"revoked_at": nil,
+		"$or": []bson.M{
+			{"expires_at": nil},
+			{"expires_at": bson.M{"$gt": now}},
+		},
+	}).Decode(&apiKey)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("unknown, revoked or expired key")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := findUser(ctx, bson.M{"_id": apiKey.UserID})
+	if err != nil || !canHoldAPIKeys(owner.Role) {
+		return nil, errors.New("key owner can no longer use API keys")
+	}
+
+	apiKeyCollection.UpdateOne(ctx, bson.M{"_id": apiKey.ID}, bson.M{"$set": bson.M{"last_used_at": now}})
+	return &apiKey, nil
+}
+
+func toAPIKeyResponse(k *APIKey) *pb.APIKey {
+	resp := &pb.APIKey{
+		Id:        k.ID.Hex(),
+		UserId:    k.UserID.Hex(),
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt.Format(time.RFC3339),
+	}
+	if k.ExpiresAt != nil {
+		resp.ExpiresAt = k.ExpiresAt.Format(time.RFC3339)
+	}
+	if k.LastUsedAt != nil {
+		resp.LastUsedAt = k.LastUsedAt.Format(time.RFC3339)
+	}
+	if k.RevokedAt != nil {
+		resp.RevokedAt = k.RevokedAt.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"time"
+
+	pb "goFinalProject/proto/proto"
+)
+
+// UserDataExport is the JSON archive returned by ExportUserData. Secrets such
+// as password and TOTP hashes are deliberately left out.
+type UserDataExport struct {
+	GeneratedAt string              `json:"generated_at"`
+	Profile     *pb.UserResponse    `json:"profile"`
+	Addresses   []*pb.Address       `json:"addresses"`
+	Sessions    []SessionExport     `json:"sessions"`
+	Orders      []*pb.OrderResponse `json:"orders"`
+}
+
+type SessionExport struct {
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+}
+
+func toSessionExport(session Session) SessionExport {
+	export := SessionExport{
+		CreatedAt: session.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: session.ExpiresAt.Format(time.RFC3339),
+	}
+	if session.RevokedAt != nil {
+		export.RevokedAt = session.RevokedAt.Format(time.RFC3339)
+	}
+	return export
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	emailIndexName    = "email_unique_ci"
+	usernameIndexName = "username_unique_ci"
+)
+
+// identityCollation compares strings ignoring case, so "Bob@Example.com" and
+// "bob@example.com" are the same account. Queries on email or username must
+// use it to match the unique indexes.
+var identityCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// EnsureIndexes creates the indexes of every collection user-service owns.
+func EnsureIndexes(ctx context.Context) error {
+	// Soft-deleted users keep their email and username until they are
+	// purged; purged tombstones and users without a username are left out by
+	// the partial filters.
+	_, err := userCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().
+				SetName(emailIndexName).
+				SetUnique(true).
+				SetCollation(identityCollation).
+				SetPartialFilterExpression(bson.M{"email": bson.M{"$gt": ""}}),
+		},
+		{
+			Keys: bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().
+				SetName(usernameIndexName).
+				SetUnique(true).
+				SetCollation(identityCollation).
+				SetPartialFilterExpression(bson.M{"username": bson.M{"$gt": ""}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Refresh and logout find the session by the hash of its token. Sessions
+	// are deleted once they expire; an expired token is refused either way.
+	_, err = sessionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reset tokens are single-use and deleted once they expire.
+	_, err = passwordResetCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Every API-key request to any service looks the key up by its hash.
+	// Expired keys are kept so their owners still see them listed.
+	_, err = apiKeyCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// BackfillVersions gives users created before versioning their first
+// version, so they can be updated like any other.
+func BackfillVersions(ctx context.Context) error {
+	_, err := userCollection.UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": int64(1)}},
+	)
+	return err
+}
+
+// duplicateUserError maps a duplicate-key error from a users write to
+// AlreadyExists. Other errors are returned as nil.
+func duplicateUserError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	switch {
+	case strings.Contains(err.Error(), emailIndexName):
+		return status.Errorf(codes.AlreadyExists, "Email already in use")
+	case strings.Contains(err.Error(), usernameIndexName):
+		return status.Errorf(codes.AlreadyExists, "Username already in use")
+	default:
+		return status.Errorf(codes.AlreadyExists, "User already exists")
+	}
+}
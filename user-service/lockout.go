@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Failed logins are counted per account and per source IP. Once a counter
+// reaches its limit every further failure doubles the lockout window.
+// Failures older than failureWindow no longer count.
+const (
+	maxUserFailures = 5
+	maxIPFailures   = 20
+	baseLockout     = time.Minute
+	maxLockout      = 24 * time.Hour
+	failureWindow   = maxLockout
+)
+
+// LoginAttempts is a failure counter in the login_attempts collection, kept
+// for one source IP or for one email that has no account.
+type LoginAttempts struct {
+	Key         string     `bson:"_id"`
+	Failures    int        `bson:"failures"`
+	LockedUntil *time.Time `bson:"locked_until,omitempty"`
+	UpdatedAt   time.Time  `bson:"updated_at"`
+}
+
+func lockoutWindow(failures, limit int) time.Duration {
+	if failures < limit {
+		return 0
+	}
+	window := baseLockout
+	for i := limit; i < failures && window < maxLockout; i++ {
+		window *= 2
+	}
+	if window > maxLockout {
+		window = maxLockout
+	}
+	return window
+}
+
+// unknownAccountKey is the login_attempts id counting failures for an email
+// without an account.
+func unknownAccountKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// lockedAttempts returns the counter under key if it is locked at now.
+func lockedAttempts(ctx context.Context, key string, now time.Time) (*LoginAttempts, error) {
+	var attempts LoginAttempts
+	err := loginAttemptCollection.FindOne(ctx, bson.M{"_id": key, "locked_until": bson.M{"$gt": now}}).Decode(&attempts)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to check login attempts: %v", err)
+	}
+	return &attempts, nil
+}
+
+func checkIPLockout(ctx context.Context, ip string, now time.Time) error {
+	if ip == "" {
+		return nil
+	}
+
+	attempts, err := lockedAttempts(ctx, ip, now)
+	if err != nil || attempts == nil {
+		return err
+	}
+	return status.Errorf(codes.ResourceExhausted, "Too many failed login attempts, try again after %s", attempts.LockedUntil.Format(time.RFC3339))
+}
+
+func checkUserLockout(user *User, now time.Time) error {
+	if user.LockedUntil == nil || !user.LockedUntil.After(now) {
+		return nil
+	}
+	return accountLockedError(*user.LockedUntil)
+}
+
+// checkUnknownAccountLockout fails for a throttled email without an account
+// exactly as checkUserLockout does for a locked account.
+func checkUnknownAccountLockout(ctx context.Context, email string, now time.Time) error {
+	attempts, err := lockedAttempts(ctx, unknownAccountKey(email), now)
+	if err != nil || attempts == nil {
+		return err
+	}
+	return accountLockedError(*attempts.LockedUntil)
+}
+
+func accountLockedError(until time.Time) error {
+	return status.Errorf(codes.ResourceExhausted, "Account is locked until %s", until.Format(time.RFC3339))
+}
+
+// bumpAttempts counts one failure under key and locks the counter once it
+// has crossed limit.
+func bumpAttempts(ctx context.Context, key string, limit int, now time.Time) error {
+	_, err := loginAttemptCollection.UpdateOne(ctx,
+		bson.M{"_id": key, "updated_at": bson.M{"$lt": now.Add(-failureWindow)}},
+		bson.M{"$set": bson.M{"failures": 0}},
+	)
+	if err != nil {
+		return err
+	}
+
+	var attempts LoginAttempts
+	err = loginAttemptCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"updated_at": now}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempts)
+	if err != nil {
+		return err
+	}
+	if window := lockoutWindow(attempts.Failures, limit); window > 0 {
+		_, err = loginAttemptCollection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"locked_until": now.Add(window)}})
+		return err
+	}
+	return nil
+}
+
+func recordUnknownAccountFailure(ctx context.Context, email string, now time.Time) error {
+	return bumpAttempts(ctx, unknownAccountKey(email), maxUserFailures, now)
+}
+
+// recordFailedLogin bumps the IP counter and, when the account is known, the
+// account counter, locking either one that has crossed its limit.
+func recordFailedLogin(ctx context.Context, userID *primitive.ObjectID, ip string, now time.Time) error {
+	if ip != "" {
+		if err := bumpAttempts(ctx, ip, maxIPFailures, now); err != nil {
+			return err
+		}
+	}
+
+	if userID != nil {
+		// An old run of failures is forgotten, like those of an address, so
+		// occasional typos never add up to a lockout.
+		_, err := userCollection.UpdateOne(ctx,
+			bson.M{"_id": *userID, "$or": bson.A{
+				bson.M{"lastFailedLoginAt": bson.M{"$lt": now.Add(-failureWindow)}},
+				bson.M{"lastFailedLoginAt": bson.M{"$exists": false}},
+			}},
+			bson.M{"$set": bson.M{"failedLogins": 0}, "$inc": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return err
+		}
+
+		var user User
+		err = userCollection.FindOneAndUpdate(ctx,
+			bson.M{"_id": *userID},
+			bson.M{"$inc": bson.M{"failedLogins": 1, "version": 1}, "$set": bson.M{"lastFailedLoginAt": now}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&user)
+		if err != nil {
+			return err
+		}
+		if window := lockoutWindow(user.FailedLogins, maxUserFailures); window > 0 {
+			_, err = userCollection.UpdateOne(ctx, bson.M{"_id": *userID}, bson.M{
+				"$set": bson.M{"lockedUntil": now.Add(window)},
+				"$inc": bson.M{"version": 1},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resetUserLockout(ctx context.Context, userID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	return userCollection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$set":   bson.M{"failedLogins": 0},
+		"$unset": bson.M{"lockedUntil": "", "lastFailedLoginAt": ""},
+		"$inc":   bson.M{"version": 1},
+	})
+}
@@ -7,8 +7,11 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"goFinalProject/auth"
+	"goFinalProject/etag"
 	pb "goFinalProject/proto/proto"
 
 	"github.com/gorilla/mux"
@@ -19,8 +22,16 @@ import (
 )
 
 var userCollection *mongo.Collection
+var sessionCollection *mongo.Collection
+var passwordResetCollection *mongo.Collection
+var loginAttemptCollection *mongo.Collection
+var apiKeyCollection *mongo.Collection
+var roleCollection *mongo.Collection
+var orderClient pb.OrderServiceClient
 
-func init() {
+// loadEnv runs from main rather than init, so tests of this package do not
+// need a .env file.
+func loadEnv() {
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -40,7 +51,21 @@ func InitMongo() {
 		log.Fatal(err)
 	}
 
-	userCollection = client.Database("go_microservices").Collection("users")
+	db := client.Database("go_microservices")
+	userCollection = db.Collection("users")
+	sessionCollection = db.Collection("sessions")
+	passwordResetCollection = db.Collection("password_resets")
+	loginAttemptCollection = db.Collection("login_attempts")
+	apiKeyCollection = db.Collection("api_keys")
+	roleCollection = db.Collection("roles")
+}
+
+func initGRPCClients() {
+	orderConn, err := grpc.Dial("localhost:50053", grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("Failed to connect to order-service: %v", err)
+	}
+	orderClient = pb.NewOrderServiceClient(orderConn)
 }
 
 func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -52,7 +77,7 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	grpcClient := pb.NewUserServiceClient(grpcDial())
-	resp, err := grpcClient.CreateUser(context.Background(), &userReq)
+	resp, err := grpcClient.CreateUser(auth.OutgoingContext(r), &userReq)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -67,19 +92,39 @@ func GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	userID := params["id"]
 
 	grpcClient := pb.NewUserServiceClient(grpcDial())
-	resp, err := grpcClient.GetUser(context.Background(), &pb.GetUserRequest{Id: userID})
+	resp, err := grpcClient.GetUser(auth.OutgoingContext(r), &pb.GetUserRequest{Id: userID})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", etag.Format(resp.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
 func GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	usersReq := pb.GetUsersRequest{
+		PageToken:     query.Get("page_token"),
+		Role:          query.Get("role"),
+		CreatedAfter:  query.Get("created_after"),
+		CreatedBefore: query.Get("created_before"),
+		Query:         query.Get("q"),
+		SortBy:        query.Get("sort_by"),
+		Descending:    query.Get("order") == "desc",
+	}
+	if pageSize := query.Get("page_size"); pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil {
+			http.Error(w, "Invalid page_size", http.StatusBadRequest)
+			return
+		}
+		usersReq.PageSize = int32(size)
+	}
+
 	grpcClient := pb.NewUserServiceClient(grpcDial())
-	resp, err := grpcClient.GetUsers(context.Background(), &pb.GetUsersRequest{})
+	resp, err := grpcClient.GetUsers(auth.OutgoingContext(r), &usersReq)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -101,14 +146,20 @@ func UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID := params["id"]
 	userReq.Id = userID
+	userReq.Version, err = etag.IfMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPreconditionRequired)
+		return
+	}
 
 	grpcClient := pb.NewUserServiceClient(grpcDial())
-	resp, err := grpcClient.UpdateUser(context.Background(), &userReq)
+	resp, err := grpcClient.UpdateUser(auth.OutgoingContext(r), &userReq)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), etag.ErrorStatus(err))
 		return
 	}
 
+	w.Header().Set("ETag", etag.Format(resp.Version))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -118,7 +169,525 @@ func DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	userID := params["id"]
 
 	grpcClient := pb.NewUserServiceClient(grpcDial())
-	resp, err := grpcClient.DeleteUser(context.Background(), &pb.DeleteUserRequest{Id: userID})
+	resp, err := grpcClient.DeleteUser(auth.OutgoingContext(r), &pb.DeleteUserRequest{Id: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func RestoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.RestoreUser(auth.OutgoingContext(r), &pb.RestoreUserRequest{Id: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func PurgeUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.PurgeUser(auth.OutgoingContext(r), &pb.PurgeUserRequest{Id: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ExportUserDataHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.ExportUserData(auth.OutgoingContext(r), &pb.ExportUserDataRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\"user-"+userID+".json\"")
+	w.Write(resp.Archive)
+}
+
+func EraseUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.EraseUser(auth.OutgoingContext(r), &pb.EraseUserRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func AddAddressHandler(w http.ResponseWriter, r *http.Request) {
+	var address pb.Address
+	err := json.NewDecoder(r.Body).Decode(&address)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.AddAddress(auth.OutgoingContext(r), &pb.AddAddressRequest{UserId: userID, Address: &address})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func GetAddressHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+	addressID := params["addressId"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.GetAddress(auth.OutgoingContext(r), &pb.GetAddressRequest{UserId: userID, AddressId: addressID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ListAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.ListAddresses(auth.OutgoingContext(r), &pb.ListAddressesRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func UpdateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	var address pb.Address
+	err := json.NewDecoder(r.Body).Decode(&address)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := mux.Vars(r)
+	userID := params["id"]
+	addressID := params["addressId"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.UpdateAddress(auth.OutgoingContext(r), &pb.UpdateAddressRequest{UserId: userID, AddressId: addressID, Address: &address})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func DeleteAddressHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+	addressID := params["addressId"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.DeleteAddress(auth.OutgoingContext(r), &pb.DeleteAddressRequest{UserId: userID, AddressId: addressID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req pb.CreateAPIKeyRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := mux.Vars(r)
+	req.UserId = params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.CreateAPIKey(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.ListAPIKeys(auth.OutgoingContext(r), &pb.ListAPIKeysRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+	keyID := params["keyId"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.RevokeAPIKey(auth.OutgoingContext(r), &pb.RevokeAPIKeyRequest{UserId: userID, KeyId: keyID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func AssignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var req pb.AssignRoleRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := mux.Vars(r)
+	req.UserId = params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.AssignRole(auth.OutgoingContext(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func RevokeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+	role := params["role"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.RevokeRole(auth.OutgoingContext(r), &pb.RevokeRoleRequest{UserId: userID, Role: role})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ListPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.ListPermissions(auth.OutgoingContext(r), &pb.ListPermissionsRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var loginReq pb.LoginRequest
+	err := json.NewDecoder(r.Body).Decode(&loginReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.Login(auth.OutgoingContext(r), &loginReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var refreshReq pb.RefreshTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&refreshReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.RefreshToken(auth.OutgoingContext(r), &refreshReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var logoutReq pb.LogoutRequest
+	err := json.NewDecoder(r.Body).Decode(&logoutReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.Logout(auth.OutgoingContext(r), &logoutReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.LogoutAll(auth.OutgoingContext(r), &pb.LogoutAllRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var passwordReq pb.ChangePasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&passwordReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := mux.Vars(r)
+	passwordReq.UserId = params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.ChangePassword(auth.OutgoingContext(r), &passwordReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var resetReq pb.RequestPasswordResetRequest
+	err := json.NewDecoder(r.Body).Decode(&resetReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.RequestPasswordReset(auth.OutgoingContext(r), &resetReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ConfirmPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var resetReq pb.ConfirmPasswordResetRequest
+	err := json.NewDecoder(r.Body).Decode(&resetReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.ConfirmPasswordReset(auth.OutgoingContext(r), &resetReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func SendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.SendVerification(auth.OutgoingContext(r), &pb.SendVerificationRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var verifyReq pb.VerifyEmailRequest
+	err := json.NewDecoder(r.Body).Decode(&verifyReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.VerifyEmail(auth.OutgoingContext(r), &verifyReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.EnrollTOTP(auth.OutgoingContext(r), &pb.EnrollTOTPRequest{UserId: userID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var totpReq pb.ConfirmTOTPRequest
+	err := json.NewDecoder(r.Body).Decode(&totpReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := mux.Vars(r)
+	totpReq.UserId = params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.ConfirmTOTP(auth.OutgoingContext(r), &totpReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var totpReq pb.DisableTOTPRequest
+	err := json.NewDecoder(r.Body).Decode(&totpReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := mux.Vars(r)
+	totpReq.UserId = params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.DisableTOTP(auth.OutgoingContext(r), &totpReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func VerifyLoginTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var totpReq pb.VerifyLoginTOTPRequest
+	err := json.NewDecoder(r.Body).Decode(&totpReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.VerifyLoginTOTP(auth.OutgoingContext(r), &totpReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+
+	grpcClient := pb.NewUserServiceClient(grpcDial())
+	resp, err := grpcClient.UnlockUser(auth.OutgoingContext(r), &pb.UnlockUserRequest{UserId: userID})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -136,14 +705,131 @@ func grpcDial() *grpc.ClientConn {
 	return conn
 }
 
+var authPolicy = auth.Policy{
+	pb.UserService_CreateUser_FullMethodName: {Public: true},
+	// order-service looks up the user and address of an order as itself.
+	pb.UserService_GetUser_FullMethodName: {
+		Roles:  []string{auth.RoleAdmin, auth.RoleService},
+		Owner:  func(req interface{}) string { return req.(*pb.GetUserRequest).Id },
+		Scopes: []string{auth.ScopeUsersRead},
+	},
+	pb.UserService_GetUsers_FullMethodName: {Roles: []string{auth.RoleAdmin}, Scopes: []string{auth.ScopeUsersRead}},
+	pb.UserService_UpdateUser_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.UpdateUserRequest).Id },
+	},
+	pb.UserService_DeleteUser_FullMethodName:   {Roles: []string{auth.RoleAdmin}},
+	pb.UserService_RestoreUser_FullMethodName:  {Roles: []string{auth.RoleAdmin}},
+	pb.UserService_PurgeUser_FullMethodName:    {Roles: []string{auth.RoleAdmin}},
+	pb.UserService_Login_FullMethodName:        {Public: true},
+	pb.UserService_RefreshToken_FullMethodName: {Public: true},
+	pb.UserService_Logout_FullMethodName:       {Public: true},
+	pb.UserService_LogoutAll_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.LogoutAllRequest).UserId },
+	},
+	pb.UserService_ChangePassword_FullMethodName: {
+		Owner: func(req interface{}) string { return req.(*pb.ChangePasswordRequest).UserId },
+	},
+	pb.UserService_RequestPasswordReset_FullMethodName: {Public: true},
+	pb.UserService_ConfirmPasswordReset_FullMethodName: {Public: true},
+	pb.UserService_SendVerification_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.SendVerificationRequest).UserId },
+	},
+	pb.UserService_VerifyEmail_FullMethodName: {Public: true},
+	pb.UserService_EnrollTOTP_FullMethodName: {
+		Owner: func(req interface{}) string { return req.(*pb.EnrollTOTPRequest).UserId },
+	},
+	pb.UserService_ConfirmTOTP_FullMethodName: {
+		Owner: func(req interface{}) string { return req.(*pb.ConfirmTOTPRequest).UserId },
+	},
+	pb.UserService_DisableTOTP_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.DisableTOTPRequest).UserId },
+	},
+	pb.UserService_VerifyLoginTOTP_FullMethodName: {Public: true},
+	pb.UserService_UnlockUser_FullMethodName:      {Roles: []string{auth.RoleAdmin}},
+	pb.UserService_ExportUserData_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.ExportUserDataRequest).UserId },
+	},
+	pb.UserService_EraseUser_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.EraseUserRequest).UserId },
+	},
+	pb.UserService_AddAddress_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.AddAddressRequest).UserId },
+	},
+	pb.UserService_GetAddress_FullMethodName: {
+		Roles:  []string{auth.RoleAdmin, auth.RoleService},
+		Owner:  func(req interface{}) string { return req.(*pb.GetAddressRequest).UserId },
+		Scopes: []string{auth.ScopeUsersRead},
+	},
+	pb.UserService_ListAddresses_FullMethodName: {
+		Roles:  []string{auth.RoleAdmin},
+		Owner:  func(req interface{}) string { return req.(*pb.ListAddressesRequest).UserId },
+		Scopes: []string{auth.ScopeUsersRead},
+	},
+	pb.UserService_UpdateAddress_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.UpdateAddressRequest).UserId },
+	},
+	pb.UserService_DeleteAddress_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.DeleteAddressRequest).UserId },
+	},
+	// CreateAPIKey also requires the key owner to be an admin or integration user.
+	pb.UserService_CreateAPIKey_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.CreateAPIKeyRequest).UserId },
+	},
+	pb.UserService_ListAPIKeys_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.ListAPIKeysRequest).UserId },
+	},
+	pb.UserService_RevokeAPIKey_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.RevokeAPIKeyRequest).UserId },
+	},
+	pb.UserService_AuthenticateAPIKey_FullMethodName: {Public: true},
+	pb.UserService_AssignRole_FullMethodName:         {Roles: []string{auth.RoleAdmin}},
+	pb.UserService_RevokeRole_FullMethodName:         {Roles: []string{auth.RoleAdmin}},
+	pb.UserService_ListPermissions_FullMethodName: {
+		Roles: []string{auth.RoleAdmin},
+		Owner: func(req interface{}) string { return req.(*pb.ListPermissionsRequest).UserId },
+	},
+}
+
 func main() {
+	loadEnv()
 	InitMongo()
+	InitMailer()
+	initGRPCClients()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := BackfillVersions(ctx); err != nil {
+		log.Fatalf("Failed to backfill user versions: %v", err)
+	}
+	if err := EnsureRoles(ctx); err != nil {
+		log.Fatalf("Failed to create roles: %v", err)
+	}
+	cancel()
+
+	secret, err := auth.Secret()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	lis, err := net.Listen("tcp", ":50051")
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryServerInterceptor(secret, validateAPIKey, authPolicy)))
 	pb.RegisterUserServiceServer(grpcServer, &UserServiceServer{})
 	go func() {
 		log.Println("gRPC server started on port :50051")
@@ -154,10 +840,39 @@ func main() {
 
 	r := mux.NewRouter()
 	r.HandleFunc("/api/users", CreateUserHandler).Methods("POST")
+	r.HandleFunc("/api/users/login", LoginHandler).Methods("POST")
+	r.HandleFunc("/api/users/login/totp", VerifyLoginTOTPHandler).Methods("POST")
+	r.HandleFunc("/api/users/refresh", RefreshTokenHandler).Methods("POST")
+	r.HandleFunc("/api/users/logout", LogoutHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/logout-all", LogoutAllHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/password", ChangePasswordHandler).Methods("PUT")
+	r.HandleFunc("/api/users/password-reset", RequestPasswordResetHandler).Methods("POST")
+	r.HandleFunc("/api/users/password-reset/confirm", ConfirmPasswordResetHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/verification", SendVerificationHandler).Methods("POST")
+	r.HandleFunc("/api/users/verify-email", VerifyEmailHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/totp", EnrollTOTPHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/totp/confirm", ConfirmTOTPHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/totp", DisableTOTPHandler).Methods("DELETE")
+	r.HandleFunc("/api/users/{id}/unlock", UnlockUserHandler).Methods("POST")
 	r.HandleFunc("/api/users/{id}", GetUserHandler).Methods("GET")
 	r.HandleFunc("/api/users", GetAllUsersHandler).Methods("GET")
 	r.HandleFunc("/api/users/{id}", UpdateUserHandler).Methods("PUT")
 	r.HandleFunc("/api/users/{id}", DeleteUserHandler).Methods("DELETE")
+	r.HandleFunc("/api/users/{id}/restore", RestoreUserHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/purge", PurgeUserHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/export", ExportUserDataHandler).Methods("GET")
+	r.HandleFunc("/api/users/{id}/erase", EraseUserHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/addresses", AddAddressHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/addresses", ListAddressesHandler).Methods("GET")
+	r.HandleFunc("/api/users/{id}/addresses/{addressId}", GetAddressHandler).Methods("GET")
+	r.HandleFunc("/api/users/{id}/addresses/{addressId}", UpdateAddressHandler).Methods("PUT")
+	r.HandleFunc("/api/users/{id}/addresses/{addressId}", DeleteAddressHandler).Methods("DELETE")
+	r.HandleFunc("/api/users/{id}/api-keys", CreateAPIKeyHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/api-keys", ListAPIKeysHandler).Methods("GET")
+	r.HandleFunc("/api/users/{id}/api-keys/{keyId}", RevokeAPIKeyHandler).Methods("DELETE")
+	r.HandleFunc("/api/users/{id}/roles", AssignRoleHandler).Methods("POST")
+	r.HandleFunc("/api/users/{id}/roles/{role}", RevokeRoleHandler).Methods("DELETE")
+	r.HandleFunc("/api/users/{id}/permissions", ListPermissionsHandler).Methods("GET")
 
 	http.Handle("/", r)
 
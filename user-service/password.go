@@ -0,0 +1,31 @@
+package main
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	minPasswordLength = 8
+	passwordResetTTL  = time.Hour
+)
+
+// PasswordReset is a single-use reset token stored in the password_resets collection.
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	TokenHash string             `bson:"token_hash"`
+	CreatedAt time.Time          `bson:"created_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return status.Errorf(codes.InvalidArgument, "Password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
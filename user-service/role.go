@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"goFinalProject/auth"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Permissions name the actions a role grants. Access is still enforced by
+// role in each service's policy; permissions let clients decide which
+// actions to offer.
+const (
+	PermAccountManage = "account:manage"
+	PermOrdersOwn     = "orders:own"
+	PermUsersRead     = "users:read"
+	PermUsersManage   = "users:manage"
+	PermRolesAssign   = "roles:assign"
+	PermProductsWrite = "products:write"
+	PermOrdersRead    = "orders:read"
+	PermOrdersWrite   = "orders:write"
+	PermAPIKeysManage = "api_keys:manage"
+)
+
+// Role is a document in the roles collection, keyed by its name.
+type Role struct {
+	Name        string    `bson:"_id"`
+	Description string    `bson:"description"`
+	Permissions []string  `bson:"permissions"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+// builtinRoles are created at startup when missing. Existing documents are
+// left alone so permission sets edited in the database survive restarts.
+var builtinRoles = []Role{
+	{
+		Name:        auth.RoleCustomer,
+		Description: "Default role for self-registered users",
+		Permissions: []string{PermAccountManage, PermOrdersOwn},
+	},
+	{
+		Name:        auth.RoleIntegration,
+		Description: "Machine accounts that hold API keys",
+		Permissions: []string{PermAccountManage, PermAPIKeysManage, PermProductsWrite, PermOrdersRead, PermOrdersWrite, PermUsersRead},
+	},
+	{
+		Name:        auth.RoleAdmin,
+		Description: "Full access",
+		Permissions: []string{
+			PermAccountManage, PermOrdersOwn, PermUsersRead, PermUsersManage, PermRolesAssign,
+			PermProductsWrite, PermOrdersRead, PermOrdersWrite, PermAPIKeysManage,
+		},
+	},
+}
+
+func EnsureRoles(ctx context.Context) error {
+	now := time.Now()
+	for _, role := range builtinRoles {
+		role.CreatedAt = now
+		_, err := roleCollection.UpdateOne(ctx,
+			bson.M{"_id": role.Name},
+			bson.M{"$setOnInsert": role},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findRole(ctx context.Context, name string) (*Role, error) {
+	var role Role
+	err := roleCollection.FindOne(ctx, bson.M{"_id": name}).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown role: %s", name)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch role: %v", err)
+	}
+	return &role, nil
+}
+
+// signupRole decides the role of a new user. Anyone may register as a
+// customer; any other role needs an admin caller.
+func signupRole(ctx context.Context, requested string) (string, error) {
+	if requested == "" || requested == auth.RoleCustomer {
+		return auth.RoleCustomer, nil
+	}
+
+	claims, _ := auth.ClaimsFromContext(ctx)
+	if claims == nil || !claims.HasRole(auth.RoleAdmin) {
+		return "", status.Errorf(codes.PermissionDenied, "Only admins can create users with role %s", requested)
+	}
+	if _, err := findRole(ctx, requested); err != nil {
+		return "", err
+	}
+	return requested, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// Session is a refresh token stored in the sessions collection. Every rotation
+// creates a new session in the same family, so a reused token can revoke all
+// of its descendants at once.
+type Session struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `bson:"user_id"`
+	FamilyID   primitive.ObjectID  `bson:"family_id"`
+	TokenHash  string              `bson:"token_hash"`
+	CreatedAt  time.Time           `bson:"created_at"`
+	ExpiresAt  time.Time           `bson:"expires_at"`
+	RevokedAt  *time.Time          `bson:"revoked_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by,omitempty"`
+}
+
+// newOpaqueToken returns a random URL-safe token and the hash stored in its place.
+func newOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func createSession(ctx context.Context, userID, familyID primitive.ObjectID, now time.Time) (*Session, string, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session := &Session{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	}
+	if _, err := sessionCollection.InsertOne(ctx, session); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+func revokeSessions(ctx context.Context, filter bson.M, now time.Time) (int64, error) {
+	filter["revoked_at"] = nil
+	res, err := sessionCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// issueTokens signs an access token and starts a new refresh session in the given family.
+func issueTokens(ctx context.Context, userID primitive.ObjectID, role string, familyID primitive.ObjectID, now time.Time) (*pb.TokenResponse, *Session, error) {
+	accessToken, err := issueAccessToken(userID.Hex(), role, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, refreshToken, err := createSession(ctx, userID, familyID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &pb.TokenResponse{
+		AccessToken:      accessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(accessTokenTTL.Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int64(refreshTokenTTL.Seconds()),
+	}, session, nil
+}
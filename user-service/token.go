@@ -0,0 +1,92 @@
+package main
+
+import (
+	"time"
+
+	"goFinalProject/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	accessTokenTTL       = 15 * time.Minute
+	verificationTokenTTL = 24 * time.Hour
+	mfaTokenTTL          = 5 * time.Minute
+
+	verificationAudience = "verify-email"
+	mfaAudience          = "mfa"
+)
+
+// issueAccessToken signs a short-lived token identifying the user and their role.
+func issueAccessToken(userID, role string, now time.Time) (string, error) {
+	secret, err := auth.Secret()
+	if err != nil {
+		return "", err
+	}
+	return auth.IssueAccessToken(secret, userID, role, now, accessTokenTTL)
+}
+
+// VerificationClaims binds a verification token to the address it was sent
+// to, so changing the email invalidates tokens already in flight.
+type VerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+func issueVerificationToken(userID, email string, now time.Time) (string, error) {
+	return signToken(VerificationClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{verificationAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(verificationTokenTTL)),
+		},
+	})
+}
+
+func parseVerificationToken(token string) (*VerificationClaims, error) {
+	var claims VerificationClaims
+	if err := parseToken(token, &claims, verificationAudience); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// issueMFAToken proves the password step of a login succeeded; it is only
+// good for finishing that login with a second factor.
+func issueMFAToken(userID string, now time.Time) (string, error) {
+	return signToken(jwt.RegisteredClaims{
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{mfaAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenTTL)),
+	})
+}
+
+func parseMFAToken(token string) (string, error) {
+	var claims jwt.RegisteredClaims
+	if err := parseToken(token, &claims, mfaAudience); err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
+
+func signToken(claims jwt.Claims) (string, error) {
+	secret, err := auth.Secret()
+	if err != nil {
+		return "", err
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+func parseToken(token string, claims jwt.Claims, audience string) error {
+	secret, err := auth.Secret()
+	if err != nil {
+		return err
+	}
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithAudience(audience), jwt.WithTimeFunc(clock))
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of RFC 6238 Appendix B, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// fixClock makes clock return t for the rest of the test.
+func fixClock(t *testing.T, at time.Time) {
+	t.Helper()
+	saved := clock
+	clock = func() time.Time { return at }
+	t.Cleanup(func() { clock = saved })
+}
+
+func mustCode(t *testing.T, step int64) string {
+	t.Helper()
+	code, err := totpCode(rfcSecret, step)
+	if err != nil {
+		t.Fatalf("totpCode(%d): %v", step, err)
+	}
+	return code
+}
+
+// The RFC lists 8-digit codes; with 6 digits they keep their last six.
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		if got := mustCode(t, totpStep(time.Unix(v.unix, 0))); got != v.code {
+			t.Errorf("code at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestVerifyTOTPSkewWindow(t *testing.T) {
+	fixClock(t, time.Unix(1111111111, 0))
+	current := totpStep(clock())
+
+	for offset := int64(-2); offset <= 2; offset++ {
+		step, ok := verifyTOTP(rfcSecret, mustCode(t, current+offset), clock(), 0)
+		want := offset >= -totpSkew && offset <= totpSkew
+		if ok != want {
+			t.Errorf("offset %d: accepted = %v, want %v", offset, ok, want)
+		}
+		if ok && step != current+offset {
+			t.Errorf("offset %d: matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestVerifyTOTPRejectsReplay(t *testing.T) {
+	fixClock(t, time.Unix(1234567890, 0))
+	code := mustCode(t, totpStep(clock()))
+
+	step, ok := verifyTOTP(rfcSecret, code, clock(), 0)
+	if !ok {
+		t.Fatal("fresh code was rejected")
+	}
+	if _, ok := verifyTOTP(rfcSecret, code, clock(), step); ok {
+		t.Error("code was accepted again after its step was used")
+	}
+
+	// Half a period later the same code is still inside the window, but its
+	// step has been used.
+	fixClock(t, clock().Add(totpPeriod/2))
+	if _, ok := verifyTOTP(rfcSecret, code, clock(), step); ok {
+		t.Error("code was accepted again later in the skew window")
+	}
+
+	// An older code from the window is rejected too once a later step was used.
+	if _, ok := verifyTOTP(rfcSecret, mustCode(t, step-1), clock(), step); ok {
+		t.Error("earlier step was accepted after a later one was used")
+	}
+}
+
+func TestVerifyTOTPRejectsMalformedCodes(t *testing.T) {
+	fixClock(t, time.Unix(59, 0))
+	for _, code := range []string{"", "28708", "2870820", "94287082"} {
+		if _, ok := verifyTOTP(rfcSecret, code, clock(), 0); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+}
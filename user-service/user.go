@@ -1 +1,179 @@
 package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "goFinalProject/proto/proto"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// User is a document in the users collection.
+type User struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	Name              string             `bson:"name"`
+	Username          string             `bson:"username"`
+	Email             string             `bson:"email"`
+	Password          string             `bson:"password"`
+	Phone             string             `bson:"phone"`
+	Role              string             `bson:"role"`
+	Verified          bool               `bson:"verified"`
+	VerifiedAt        *time.Time         `bson:"verifiedAt,omitempty"`
+	FailedLogins      int                `bson:"failedLogins,omitempty"`
+	LockedUntil       *time.Time         `bson:"lockedUntil,omitempty"`
+	LastFailedLoginAt *time.Time         `bson:"lastFailedLoginAt,omitempty"`
+	TOTPEnabled       bool               `bson:"totpEnabled,omitempty"`
+	TOTPSecret        string             `bson:"totpSecret,omitempty"`
+	TOTPPendingSecret string             `bson:"totpPendingSecret,omitempty"`
+	TOTPLastStep      int64              `bson:"totpLastStep,omitempty"`
+	RecoveryCodes     []string           `bson:"recoveryCodes,omitempty"`
+	Addresses         []Address          `bson:"addresses,omitempty"`
+	CreatedAt         time.Time          `bson:"createdAt"`
+	UpdatedAt         time.Time          `bson:"updatedAt"`
+	DeletedAt         *time.Time         `bson:"deletedAt,omitempty"`
+	PurgedAt          *time.Time         `bson:"purgedAt,omitempty"`
+	ErasureStartedAt  *time.Time         `bson:"erasureStartedAt,omitempty"`
+	Version           int64              `bson:"version"`
+}
+
+// personalUserFields are erased when a deleted user is purged. The _id and
+// timestamps stay behind as a tombstone so orders keep a valid user id.
+var personalUserFields = []string{
+	"name", "username", "email", "password", "phone", "role",
+	"verified", "verifiedAt", "failedLogins", "lockedUntil", "lastFailedLoginAt",
+	"totpEnabled", "totpSecret", "totpPendingSecret", "totpLastStep", "recoveryCodes",
+	"addresses",
+}
+
+// defaultUserRetention is how long a soft-deleted user can still be restored.
+const defaultUserRetention = 30 * 24 * time.Hour
+
+func userRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("USER_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		return defaultUserRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// notDeleted narrows a filter to users that have not been soft-deleted.
+func notDeleted(filter bson.M) bson.M {
+	filter["deletedAt"] = nil
+	return filter
+}
+
+// userSortFields maps the sort_by values accepted by GetUsers to document fields.
+var userSortFields = map[string]string{
+	"":           "createdAt",
+	"created_at": "createdAt",
+	"name":       "name",
+	"email":      "email",
+}
+
+func (u *User) sortValue(field string) interface{} {
+	switch field {
+	case "name":
+		return u.Name
+	case "email":
+		return u.Email
+	default:
+		return u.CreatedAt
+	}
+}
+
+// requiredUserFields must be present for a document to be served at all;
+// everything else falls back to its zero value.
+var requiredUserFields = []string{"_id", "name", "email", "createdAt"}
+
+// findUser loads a single user that has not been deleted and maps failures to
+// gRPC statuses: NotFound when nothing matches, DataLoss when the stored
+// document is unusable. Email and username match case-insensitively.
+func findUser(ctx context.Context, filter bson.M) (*User, error) {
+	opts := options.FindOne().SetCollation(identityCollation)
+	raw, err := userCollection.FindOne(ctx, notDeleted(filter), opts).Raw()
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "User not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch user: %v", err)
+	}
+	return decodeUser(raw)
+}
+
+func decodeUser(raw bson.Raw) (*User, error) {
+	id := ""
+	if v, err := raw.LookupErr("_id"); err == nil {
+		if oid, ok := v.ObjectIDOK(); ok {
+			id = oid.Hex()
+		}
+	}
+
+	var missing []string
+	for _, field := range requiredUserFields {
+		if _, err := raw.LookupErr(field); err != nil {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, invalidUserError(id, "missing fields: "+strings.Join(missing, ", "), map[string]string{
+			"missing_fields": strings.Join(missing, ","),
+		})
+	}
+
+	var user User
+	if err := bson.Unmarshal(raw, &user); err != nil {
+		return nil, invalidUserError(id, err.Error(), nil)
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = user.CreatedAt
+	}
+	return &user, nil
+}
+
+func invalidUserError(id, reason string, metadata map[string]string) error {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["user_id"] = id
+
+	st := status.Newf(codes.DataLoss, "User %s has an invalid document: %s", id, reason)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   "INVALID_USER_DOCUMENT",
+		Domain:   "user-service",
+		Metadata: metadata,
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// toUserResponse is the single mapping from a stored user to the API shape.
+func toUserResponse(user *User) *pb.UserResponse {
+	resp := &pb.UserResponse{
+		Id:        user.ID.Hex(),
+		Name:      user.Name,
+		Username:  user.Username,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
+		Verified:  user.Verified,
+		Version:   user.Version,
+	}
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		resp.LockedUntil = user.LockedUntil.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"goFinalProject/auth"
+	"goFinalProject/pagination"
 	pb "goFinalProject/proto/proto"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -17,20 +23,9 @@ type UserServiceServer struct {
 }
 
 func (s *UserServiceServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.UserResponse, error) {
-	emailCount, err := userCollection.CountDocuments(ctx, bson.M{"email": req.Email})
+	role, err := signupRole(ctx, req.Role)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Error checking email uniqueness: %v", err)
-	}
-	if emailCount > 0 {
-		return nil, status.Errorf(codes.AlreadyExists, "Email already in use")
-	}
-
-	usernameCount, err := userCollection.CountDocuments(ctx, bson.M{"username": req.Username})
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Error checking username uniqueness: %v", err)
-	}
-	if usernameCount > 0 {
-		return nil, status.Errorf(codes.AlreadyExists, "Username already in use")
+		return nil, err
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -40,34 +35,28 @@ func (s *UserServiceServer) CreateUser(ctx context.Context, req *pb.CreateUserRe
 
 	now := time.Now()
 
-	user := bson.M{
-		"name":      req.Name,
-		"username":  req.Username,
-		"email":     req.Email,
-		"password":  string(hashedPassword),
-		"phone":     req.Phone,
-		"role":      req.Role,
-		"createdAt": now,
-		"updatedAt": now,
+	user := &User{
+		ID:        primitive.NewObjectID(),
+		Name:      req.Name,
+		Username:  req.Username,
+		Email:     req.Email,
+		Password:  string(hashedPassword),
+		Phone:     req.Phone,
+		Role:      role,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
 	}
 
-	res, err := userCollection.InsertOne(ctx, user)
+	_, err = userCollection.InsertOne(ctx, user)
+	if dup := duplicateUserError(err); dup != nil {
+		return nil, dup
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to insert user: %v", err)
 	}
 
-	oid := res.InsertedID.(primitive.ObjectID)
-
-	return &pb.UserResponse{
-		Id:        oid.Hex(),
-		Name:      req.Name,
-		Username:  req.Username,
-		Email:     req.Email,
-		Phone:     req.Phone,
-		Role:      req.Role,
-		CreatedAt: now.Format(time.RFC3339),
-		UpdatedAt: now.Format(time.RFC3339),
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *UserServiceServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.UserResponse, error) {
@@ -76,55 +65,109 @@ func (s *UserServiceServer) GetUser(ctx context.Context, req *pb.GetUserRequest)
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
 	}
 
-	var user bson.M
-	err = userCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&user)
+	user, err := findUser(ctx, bson.M{"_id": oid})
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, "User not found: %v", err)
-	}
-
-	name, ok1 := user["name"].(string)
-	email, ok2 := user["email"].(string)
-
-	if !ok1 || !ok2 {
-		return nil, status.Errorf(codes.Internal, "Invalid user data format")
+		return nil, err
 	}
 
-	return &pb.UserResponse{
-		Id:    oid.Hex(),
-		Name:  name,
-		Email: email,
-	}, nil
+	return toUserResponse(user), nil
 }
 
 func (s *UserServiceServer) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.UsersResponse, error) {
-	cursor, err := userCollection.Find(ctx, bson.M{})
+	sortBy, ok := userSortFields[req.SortBy]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Unsupported sort field: %s", req.SortBy)
+	}
+
+	filter, err := usersFilter(req)
 	if err != nil {
 		return nil, err
 	}
+
+	after, err := pagination.After(req.PageToken, sortBy, req.Descending)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid page token")
+	}
+
+	total, err := userCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to count users: %v", err)
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	// Fetch one extra document to learn whether another page exists.
+	opts := options.Find().
+		SetSort(pagination.Sort(sortBy, req.Descending)).
+		SetLimit(pageSize + 1)
+
+	cursor, err := userCollection.Find(ctx, bson.M{"$and": bson.A{filter, after}}, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch users: %v", err)
+	}
 	defer cursor.Close(ctx)
 
-	var users []*pb.UserResponse
+	var page []*User
 	for cursor.Next(ctx) {
-		var user bson.M
-		if err := cursor.Decode(&user); err != nil {
+		user, err := decodeUser(cursor.Current)
+		if err != nil {
 			return nil, err
 		}
-
-		oid := user["_id"].(primitive.ObjectID)
-		users = append(users, &pb.UserResponse{
-			Id:    oid.Hex(),
-			Name:  user["name"].(string),
-			Email: user["email"].(string),
-		})
+		page = append(page, user)
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, "Failed to fetch users: %v", err)
 	}
 
-	return &pb.UsersResponse{
-		Users: users,
-	}, nil
+	resp := &pb.UsersResponse{TotalCount: total}
+	if int64(len(page)) > pageSize {
+		page = page[:pageSize]
+		last := page[len(page)-1]
+		resp.NextPageToken, err = pagination.EncodeToken(sortBy, last.sortValue(sortBy), last.ID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to encode page token: %v", err)
+		}
+	}
+	for _, user := range page {
+		resp.Users = append(resp.Users, toUserResponse(user))
+	}
+
+	return resp, nil
+}
+
+func usersFilter(req *pb.GetUsersRequest) (bson.M, error) {
+	filter := notDeleted(bson.M{})
+	if req.Role != "" {
+		filter["role"] = req.Role
+	}
+
+	createdAt := bson.M{}
+	if req.CreatedAfter != "" {
+		t, err := time.Parse(time.RFC3339, req.CreatedAfter)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid created_after: %v", err)
+		}
+		createdAt["$gte"] = t
+	}
+	if req.CreatedBefore != "" {
+		t, err := time.Parse(time.RFC3339, req.CreatedBefore)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid created_before: %v", err)
+		}
+		createdAt["$lt"] = t
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	if req.Query != "" {
+		prefix := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(req.Query), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": prefix},
+			bson.M{"email": prefix},
+		}
+	}
+	return filter, nil
 }
 
 func (s *UserServiceServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
@@ -133,6 +176,10 @@ func (s *UserServiceServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRe
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
 	}
 
+	if req.Version <= 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "Version is required")
+	}
+
 	updateFields := bson.M{}
 
 	if req.Name != "" {
@@ -140,20 +187,18 @@ func (s *UserServiceServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRe
 	}
 	if req.Email != "" {
 		updateFields["email"] = req.Email
+		// A new address has to be verified again.
+		updateFields["verified"] = false
 	}
 	if req.Username != "" {
-		count, err := userCollection.CountDocuments(ctx, bson.M{"username": req.Username, "_id": bson.M{"$ne": oid}})
-		if err != nil {
-			return nil, status.Errorf(codes.Internal, "Error checking username uniqueness: %v", err)
-		}
-		if count > 0 {
-			return nil, status.Errorf(codes.AlreadyExists, "Username already in use")
-		}
 		updateFields["username"] = req.Username
 	}
 	if req.Phone != "" {
 		updateFields["phone"] = req.Phone
 	}
+	if req.Role != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Roles are changed with AssignRole and RevokeRole")
+	}
 
 	if len(updateFields) == 0 {
 		return nil, status.Errorf(codes.InvalidArgument, "No fields to update")
@@ -161,45 +206,1247 @@ func (s *UserServiceServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRe
 
 	updateFields["updatedAt"] = time.Now()
 
-	update := bson.M{"$set": updateFields}
+	update := bson.M{"$set": updateFields, "$inc": bson.M{"version": 1}}
 
-	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	res, err := userCollection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid, "version": req.Version}), update)
+	if dup := duplicateUserError(err); dup != nil {
+		return nil, dup
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to update user: %v", err)
 	}
+	if res.MatchedCount == 0 {
+		current, err := findUser(ctx, bson.M{"_id": oid})
+		if err != nil {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Aborted, "User was modified concurrently, current version is %d", current.Version)
+	}
 
 	// Fetch updated user
-	var updatedUser bson.M
-	err = userCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&updatedUser)
+	updatedUser, err := findUser(ctx, bson.M{"_id": oid})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to fetch updated user: %v", err)
+		return nil, err
 	}
 
-	return &pb.UserResponse{
-		Id:        oid.Hex(),
-		Name:      updatedUser["name"].(string),
-		Username:  updatedUser["username"].(string),
-		Email:     updatedUser["email"].(string),
-		Phone:     updatedUser["phone"].(string),
-		Role:      updatedUser["role"].(string),
-		CreatedAt: updatedUser["createdAt"].(primitive.DateTime).Time().Format(time.RFC3339),
-		UpdatedAt: updatedUser["updatedAt"].(primitive.DateTime).Time().Format(time.RFC3339),
-	}, nil
+	return toUserResponse(updatedUser), nil
 }
 
 func (s *UserServiceServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
 	oid, err := primitive.ObjectIDFromHex(req.Id)
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	now := time.Now()
+	res, err := userCollection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), bson.M{
+		"$set": bson.M{"deletedAt": now, "updatedAt": now},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete user: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "User not found")
+	}
+
+	if _, err := revokeSessions(ctx, bson.M{"user_id": oid}, now); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to revoke sessions: %v", err)
+	}
+
+	return &pb.DeleteUserResponse{
+		Id:      oid.Hex(),
+		Success: true,
+	}, nil
+}
+
+func (s *UserServiceServer) RestoreUser(ctx context.Context, req *pb.RestoreUserRequest) (*pb.UserResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	res, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": oid, "deletedAt": bson.M{"$ne": nil}, "purgedAt": nil},
+		bson.M{
+			"$set":   bson.M{"updatedAt": time.Now()},
+			"$unset": bson.M{"deletedAt": ""},
+			"$inc":   bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to restore user: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "No restorable deleted user with this id")
 	}
 
-	_, err = userCollection.DeleteOne(ctx, bson.M{"_id": oid})
+	user, err := findUser(ctx, bson.M{"_id": oid})
 	if err != nil {
 		return nil, err
 	}
 
+	return toUserResponse(user), nil
+}
+
+func (s *UserServiceServer) PurgeUser(ctx context.Context, req *pb.PurgeUserRequest) (*pb.DeleteUserResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	now := time.Now()
+	retention := userRetention()
+
+	unset := bson.M{}
+	for _, field := range personalUserFields {
+		unset[field] = ""
+	}
+	res, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": oid, "deletedAt": bson.M{"$lte": now.Add(-retention)}, "purgedAt": nil},
+		bson.M{
+			"$set":   bson.M{"purgedAt": now, "updatedAt": now},
+			"$unset": unset,
+			"$inc":   bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to purge user: %v", err)
+	}
+
+	if res.MatchedCount == 0 {
+		var tombstone struct {
+			DeletedAt *time.Time `bson:"deletedAt"`
+			PurgedAt  *time.Time `bson:"purgedAt"`
+		}
+		err := userCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&tombstone)
+		switch {
+		case err == mongo.ErrNoDocuments:
+			return nil, status.Errorf(codes.NotFound, "User not found")
+		case err != nil:
+			return nil, status.Errorf(codes.Internal, "Failed to fetch user: %v", err)
+		case tombstone.PurgedAt != nil:
+			return nil, status.Errorf(codes.FailedPrecondition, "User was already purged")
+		case tombstone.DeletedAt == nil:
+			return nil, status.Errorf(codes.FailedPrecondition, "User must be deleted before it can be purged")
+		default:
+			return nil, status.Errorf(codes.FailedPrecondition, "User can be purged after %s", tombstone.DeletedAt.Add(retention).Format(time.RFC3339))
+		}
+	}
+
+	if _, err := sessionCollection.DeleteMany(ctx, bson.M{"user_id": oid}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete sessions: %v", err)
+	}
+	if _, err := passwordResetCollection.DeleteMany(ctx, bson.M{"user_id": oid}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete reset tokens: %v", err)
+	}
+	if _, err := apiKeyCollection.DeleteMany(ctx, bson.M{"user_id": oid}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete API keys: %v", err)
+	}
+
 	return &pb.DeleteUserResponse{
 		Id:      oid.Hex(),
 		Success: true,
 	}, nil
 }
+
+func (s *UserServiceServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.TokenResponse, error) {
+	if req.Email == "" || req.Password == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Email and password are required")
+	}
+
+	now := clock()
+	ip := auth.ClientIP(ctx)
+	if err := checkIPLockout(ctx, ip, now); err != nil {
+		return nil, err
+	}
+
+	user, err := findUser(ctx, bson.M{"email": req.Email})
+	if status.Code(err) == codes.NotFound {
+		// Unknown emails are throttled like accounts, so the lockout error
+		// does not tell which emails have one.
+		if err := checkUnknownAccountLockout(ctx, req.Email, now); err != nil {
+			return nil, err
+		}
+		if err := recordFailedLogin(ctx, nil, ip, now); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to record login attempt: %v", err)
+		}
+		if err := recordUnknownAccountFailure(ctx, req.Email, now); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to record login attempt: %v", err)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid email or password")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUserLockout(user, now); err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		if err := recordFailedLogin(ctx, &user.ID, ip, now); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to record login attempt: %v", err)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid email or password")
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := issueMFAToken(user.ID.Hex(), now)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to issue MFA token: %v", err)
+		}
+		return &pb.TokenResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+			ExpiresIn:   int64(mfaTokenTTL.Seconds()),
+		}, nil
+	}
+
+	return completeLogin(ctx, user, now)
+}
+
+// completeLogin starts a new session for a user who passed every login step.
+func completeLogin(ctx context.Context, user *User, now time.Time) (*pb.TokenResponse, error) {
+	if _, err := resetUserLockout(ctx, user.ID); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to reset login attempts: %v", err)
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+
+	resp, _, err := issueTokens(ctx, user.ID, user.Role, primitive.NewObjectID(), now)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to issue tokens: %v", err)
+	}
+
+	resp.User = toUserResponse(user)
+	return resp, nil
+}
+
+func (s *UserServiceServer) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.TokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Refresh token is required")
+	}
+
+	now := time.Now()
+	tokenHash := hashToken(req.RefreshToken)
+
+	// Claim the session atomically so two concurrent refreshes cannot both rotate it.
+	var session Session
+	err := sessionCollection.FindOneAndUpdate(ctx,
+		bson.M{"token_hash": tokenHash, "revoked_at": nil, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		var stale Session
+		err = sessionCollection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&stale)
+		if err == mongo.ErrNoDocuments {
+			return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token")
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to fetch session: %v", err)
+		}
+		if stale.RevokedAt != nil {
+			// A revoked token being presented again means it was copied; kill the whole family.
+			if _, err := revokeSessions(ctx, bson.M{"family_id": stale.FamilyID}, now); err != nil {
+				return nil, status.Errorf(codes.Internal, "Failed to revoke sessions: %v", err)
+			}
+			return nil, status.Errorf(codes.Unauthenticated, "Refresh token reuse detected")
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "Refresh token expired")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch session: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": session.UserID})
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.Unauthenticated, "User no longer exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, next, err := issueTokens(ctx, session.UserID, user.Role, session.FamilyID, now)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to issue tokens: %v", err)
+	}
+
+	_, err = sessionCollection.UpdateOne(ctx, bson.M{"_id": session.ID}, bson.M{"$set": bson.M{"replaced_by": next.ID}})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to rotate session: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (s *UserServiceServer) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Refresh token is required")
+	}
+
+	var session Session
+	err := sessionCollection.FindOne(ctx, bson.M{"token_hash": hashToken(req.RefreshToken)}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid refresh token")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch session: %v", err)
+	}
+
+	revoked, err := revokeSessions(ctx, bson.M{"family_id": session.FamilyID}, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to revoke sessions: %v", err)
+	}
+
+	return &pb.LogoutResponse{
+		Success:         true,
+		RevokedSessions: revoked,
+	}, nil
+}
+
+func (s *UserServiceServer) LogoutAll(ctx context.Context, req *pb.LogoutAllRequest) (*pb.LogoutResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	revoked, err := revokeSessions(ctx, bson.M{"user_id": oid}, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to revoke sessions: %v", err)
+	}
+
+	return &pb.LogoutResponse{
+		Success:         true,
+		RevokedSessions: revoked,
+	}, nil
+}
+
+func (s *UserServiceServer) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.PasswordResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)) != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "Old password is incorrect")
+	}
+
+	if err := setPassword(ctx, oid, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	return &pb.PasswordResponse{Success: true}, nil
+}
+
+func (s *UserServiceServer) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.PasswordResponse, error) {
+	if req.Email == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Email is required")
+	}
+
+	user, err := findUser(ctx, bson.M{"email": req.Email})
+	if status.Code(err) == codes.NotFound {
+		// Answer the same way for unknown emails so the endpoint cannot be used to probe accounts.
+		return &pb.PasswordResponse{Success: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	oid := user.ID
+
+	now := time.Now()
+
+	// Only the most recently requested token stays usable.
+	_, err = passwordResetCollection.UpdateMany(ctx,
+		bson.M{"user_id": oid, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": now}},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to invalidate old reset tokens: %v", err)
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate reset token: %v", err)
+	}
+
+	_, err = passwordResetCollection.InsertOne(ctx, PasswordReset{
+		UserID:    oid,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetTTL),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to store reset token: %v", err)
+	}
+
+	err = mailer.Send(ctx, Mail{
+		To:      req.Email,
+		Subject: "Reset your password",
+		Body:    "Use this token to reset your password: " + token + "\r\nIt expires in " + passwordResetTTL.String() + ".",
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to send reset email: %v", err)
+	}
+
+	return &pb.PasswordResponse{Success: true}, nil
+}
+
+func (s *UserServiceServer) ConfirmPasswordReset(ctx context.Context, req *pb.ConfirmPasswordResetRequest) (*pb.PasswordResponse, error) {
+	if req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Reset token is required")
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var reset PasswordReset
+	err := passwordResetCollection.FindOneAndUpdate(ctx,
+		bson.M{"token_hash": hashToken(req.Token), "used_at": nil, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid or expired reset token")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch reset token: %v", err)
+	}
+
+	if err := setPassword(ctx, reset.UserID, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	return &pb.PasswordResponse{Success: true}, nil
+}
+
+// setPassword stores a new bcrypt hash and signs the user out everywhere.
+func setPassword(ctx context.Context, oid primitive.ObjectID, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to hash password: %v", err)
+	}
+
+	now := time.Now()
+	res, err := userCollection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), bson.M{
+		"$set": bson.M{
+			"password":     string(hashedPassword),
+			"failedLogins": 0,
+			"updatedAt":    now,
+		},
+		"$unset": bson.M{"lockedUntil": "", "lastFailedLoginAt": ""},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to update password: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return status.Errorf(codes.NotFound, "User not found")
+	}
+
+	if _, err := revokeSessions(ctx, bson.M{"user_id": oid}, now); err != nil {
+		return status.Errorf(codes.Internal, "Failed to revoke sessions: %v", err)
+	}
+	return nil
+}
+
+func (s *UserServiceServer) SendVerification(ctx context.Context, req *pb.SendVerificationRequest) (*pb.VerificationResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Verified {
+		return &pb.VerificationResponse{Success: true, Verified: true}, nil
+	}
+	if user.Email == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "User has no email address")
+	}
+
+	token, err := issueVerificationToken(oid.Hex(), user.Email, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to issue verification token: %v", err)
+	}
+
+	err = mailer.Send(ctx, Mail{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    "Use this token to verify your email address: " + token + "\r\nIt expires in " + verificationTokenTTL.String() + ".",
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to send verification email: %v", err)
+	}
+
+	return &pb.VerificationResponse{Success: true}, nil
+}
+
+func (s *UserServiceServer) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerificationResponse, error) {
+	if req.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Verification token is required")
+	}
+
+	claims, err := parseVerificationToken(req.Token)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid verification token: %v", err)
+	}
+	oid, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid verification token: %v", err)
+	}
+
+	now := time.Now()
+	res, err := userCollection.UpdateOne(ctx,
+		notDeleted(bson.M{"_id": oid, "email": claims.Email}),
+		bson.M{
+			"$set": bson.M{"verified": true, "verifiedAt": now, "updatedAt": now},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to verify email: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Verification token no longer matches the account email")
+	}
+
+	return &pb.VerificationResponse{Success: true, Verified: true}, nil
+}
+
+func (s *UserServiceServer) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, status.Errorf(codes.FailedPrecondition, "TOTP is already enabled")
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate TOTP secret: %v", err)
+	}
+
+	// The secret stays pending until the user proves their authenticator produces matching codes.
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{
+		"$set": bson.M{"totpPendingSecret": secret, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to store TOTP secret: %v", err)
+	}
+
+	return &pb.EnrollTOTPResponse{
+		Secret:          secret,
+		ProvisioningUri: totpURI(secret, user.Email),
+	}, nil
+}
+
+func (s *UserServiceServer) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+	secret := user.TOTPPendingSecret
+	if secret == "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "TOTP enrollment has not been started")
+	}
+
+	step, ok := verifyTOTP(secret, req.Code, clock(), 0)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid TOTP code")
+	}
+
+	recoveryCodes, recoveryHashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate recovery codes: %v", err)
+	}
+
+	res, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": oid, "totpPendingSecret": secret},
+		bson.M{
+			"$set": bson.M{
+				"totpEnabled":   true,
+				"totpSecret":    secret,
+				"totpLastStep":  step,
+				"recoveryCodes": recoveryHashes,
+				"updatedAt":     time.Now(),
+			},
+			"$unset": bson.M{"totpPendingSecret": ""},
+			"$inc":   bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to enable TOTP: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.Aborted, "TOTP enrollment changed, start again")
+	}
+
+	return &pb.ConfirmTOTPResponse{
+		Enabled:       true,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *UserServiceServer) DisableTOTP(ctx context.Context, req *pb.DisableTOTPRequest) (*pb.DisableTOTPResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, status.Errorf(codes.FailedPrecondition, "TOTP is not enabled")
+	}
+
+	// Admins may reset a lost authenticator for someone else without a code.
+	claims, _ := auth.ClaimsFromContext(ctx)
+	adminOverride := claims != nil && claims.Subject != req.UserId && claims.HasRole(auth.RoleAdmin)
+	if !adminOverride {
+		if err := checkSecondFactor(ctx, user, req.Code); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{
+		"$set":   bson.M{"totpEnabled": false, "updatedAt": time.Now()},
+		"$unset": bson.M{"totpSecret": "", "totpPendingSecret": "", "totpLastStep": "", "recoveryCodes": ""},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to disable TOTP: %v", err)
+	}
+
+	return &pb.DisableTOTPResponse{Success: true}, nil
+}
+
+func (s *UserServiceServer) VerifyLoginTOTP(ctx context.Context, req *pb.VerifyLoginTOTPRequest) (*pb.TokenResponse, error) {
+	userID, err := parseMFAToken(req.MfaToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid MFA token: %v", err)
+	}
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid MFA token: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.Unauthenticated, "User no longer exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, status.Errorf(codes.FailedPrecondition, "TOTP is not enabled")
+	}
+
+	now := clock()
+	ip := auth.ClientIP(ctx)
+	if err := checkIPLockout(ctx, ip, now); err != nil {
+		return nil, err
+	}
+	if err := checkUserLockout(user, now); err != nil {
+		return nil, err
+	}
+
+	if err := checkSecondFactor(ctx, user, req.Code); err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			if err := recordFailedLogin(ctx, &oid, ip, now); err != nil {
+				return nil, status.Errorf(codes.Internal, "Failed to record login attempt: %v", err)
+			}
+		}
+		return nil, err
+	}
+
+	return completeLogin(ctx, user, now)
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code and burns
+// whichever one was used.
+func checkSecondFactor(ctx context.Context, user *User, code string) error {
+	oid := user.ID
+	if code == "" {
+		return status.Errorf(codes.InvalidArgument, "TOTP code is required")
+	}
+
+	if step, ok := verifyTOTP(user.TOTPSecret, code, clock(), user.TOTPLastStep); ok {
+		res, err := userCollection.UpdateOne(ctx,
+			bson.M{"_id": oid, "totpLastStep": bson.M{"$lt": step}},
+			bson.M{"$set": bson.M{"totpLastStep": step}, "$inc": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return status.Errorf(codes.Internal, "Failed to record TOTP use: %v", err)
+		}
+		if res.MatchedCount == 0 {
+			return status.Errorf(codes.Unauthenticated, "TOTP code was already used")
+		}
+		return nil
+	}
+
+	res, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": oid, "recoveryCodes": hashToken(code)},
+		bson.M{"$pull": bson.M{"recoveryCodes": hashToken(code)}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to check recovery code: %v", err)
+	}
+	if res.ModifiedCount == 0 {
+		return status.Errorf(codes.Unauthenticated, "Invalid TOTP code")
+	}
+	return nil
+}
+
+func (s *UserServiceServer) UnlockUser(ctx context.Context, req *pb.UnlockUserRequest) (*pb.UnlockUserResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	res, err := resetUserLockout(ctx, oid)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to unlock user: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "User not found")
+	}
+
+	return &pb.UnlockUserResponse{Success: true}, nil
+}
+
+func (s *UserServiceServer) ExportUserData(ctx context.Context, req *pb.ExportUserDataRequest) (*pb.ExportUserDataResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := sessionCollection.Find(ctx, bson.M{"user_id": oid})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch sessions: %v", err)
+	}
+	var sessions []Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch sessions: %v", err)
+	}
+
+	// Orders live in order-service; it checks the forwarded caller against its own policy.
+	orders, err := orderClient.GetUserOrders(auth.ForwardContext(ctx), &pb.GetUserOrdersRequest{UserId: req.UserId})
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "Failed to fetch orders: %v", err)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	export := UserDataExport{
+		GeneratedAt: now,
+		Profile:     toUserResponse(user),
+		Addresses:   []*pb.Address{},
+		Sessions:    []SessionExport{},
+		Orders:      orders.Orders,
+	}
+	for _, address := range user.Addresses {
+		export.Addresses = append(export.Addresses, toAddressResponse(address))
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, toSessionExport(session))
+	}
+
+	archive, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to encode export: %v", err)
+	}
+
+	return &pb.ExportUserDataResponse{
+		Archive:     archive,
+		ContentType: "application/json",
+		GeneratedAt: now,
+	}, nil
+}
+
+// EraseUser removes a user across services. The user is marked first, so if
+// order-service cannot be reached the erasure can simply be retried; the
+// profile is only deleted once the orders no longer reference it.
+func (s *UserServiceServer) EraseUser(ctx context.Context, req *pb.EraseUserRequest) (*pb.EraseUserResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	now := time.Now()
+	res, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": oid, "purgedAt": nil},
+		bson.M{
+			"$set": bson.M{"erasureStartedAt": now, "updatedAt": now},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to start erasure: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "User not found")
+	}
+
+	// Hide the user and end their sessions straight away.
+	_, err = userCollection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), bson.M{
+		"$set": bson.M{"deletedAt": now},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete user: %v", err)
+	}
+	if _, err := revokeSessions(ctx, bson.M{"user_id": oid}, now); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to revoke sessions: %v", err)
+	}
+
+	// Only services may anonymize orders, so this call goes out as
+	// user-service rather than as the caller.
+	serviceCtx, err := auth.ServiceContext(ctx, "user-service")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to authenticate to order-service: %v", err)
+	}
+	anonymized, err := orderClient.AnonymizeUserOrders(serviceCtx, &pb.AnonymizeUserOrdersRequest{UserId: req.UserId})
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "Failed to anonymize orders, erasure can be retried: %v", err)
+	}
+
+	if _, err := sessionCollection.DeleteMany(ctx, bson.M{"user_id": oid}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete sessions: %v", err)
+	}
+	if _, err := passwordResetCollection.DeleteMany(ctx, bson.M{"user_id": oid}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete reset tokens: %v", err)
+	}
+	if _, err := apiKeyCollection.DeleteMany(ctx, bson.M{"user_id": oid}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete API keys: %v", err)
+	}
+	if _, err := userCollection.DeleteOne(ctx, bson.M{"_id": oid}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete user: %v", err)
+	}
+
+	return &pb.EraseUserResponse{
+		UserId:           req.UserId,
+		Success:          true,
+		AnonymizedOrders: anonymized.AnonymizedOrders,
+	}, nil
+}
+
+func (s *UserServiceServer) AddAddress(ctx context.Context, req *pb.AddAddressRequest) (*pb.Address, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	if req.Address == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Address is required")
+	}
+
+	address := toAddress(req.Address)
+	if err := address.validate(); err != nil {
+		return nil, err
+	}
+	address.ID = primitive.NewObjectID()
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+	if len(user.Addresses) >= maxAddresses {
+		return nil, status.Errorf(codes.FailedPrecondition, "Address book is limited to %d entries", maxAddresses)
+	}
+	if len(user.Addresses) == 0 {
+		address.DefaultShipping = true
+		address.DefaultBilling = true
+	}
+
+	// The new address is appended and takes over the defaults in one update,
+	// so a failure cannot leave the user without a default. $push cannot be
+	// combined with updates to other elements, hence the pipeline; $literal
+	// keeps user input from being read as expressions.
+	addresses := bson.M{"$ifNull": bson.A{"$addresses", bson.A{}}}
+	if cleared := clearedDefaults(address); len(cleared) > 0 {
+		addresses = bson.M{"$map": bson.M{
+			"input": addresses,
+			"as":    "other",
+			"in":    bson.M{"$mergeObjects": bson.A{"$$other", cleared}},
+		}}
+	}
+	res, err := userCollection.UpdateOne(ctx, notDeleted(bson.M{"_id": oid}), mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"addresses": bson.M{"$concatArrays": bson.A{addresses, bson.A{bson.M{"$literal": address}}}},
+			"updatedAt": time.Now(),
+			"version":   bson.M{"$add": bson.A{"$version", 1}},
+		}}},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to add address: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "User not found")
+	}
+
+	return toAddressResponse(address), nil
+}
+
+func (s *UserServiceServer) GetAddress(ctx context.Context, req *pb.GetAddressRequest) (*pb.Address, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	addressID, err := primitive.ObjectIDFromHex(req.AddressId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid address id: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range user.Addresses {
+		if address.ID == addressID {
+			return toAddressResponse(address), nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "Address not found")
+}
+
+func (s *UserServiceServer) ListAddresses(ctx context.Context, req *pb.ListAddressesRequest) (*pb.AddressesResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []*pb.Address
+	for _, address := range user.Addresses {
+		addresses = append(addresses, toAddressResponse(address))
+	}
+	return &pb.AddressesResponse{Addresses: addresses}, nil
+}
+
+func (s *UserServiceServer) UpdateAddress(ctx context.Context, req *pb.UpdateAddressRequest) (*pb.Address, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	addressID, err := primitive.ObjectIDFromHex(req.AddressId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid address id: %v", err)
+	}
+	if req.Address == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Address is required")
+	}
+
+	address := toAddress(req.Address)
+	if err := address.validate(); err != nil {
+		return nil, err
+	}
+	address.ID = addressID
+
+	// Replacing the address and clearing the defaults it takes over happen in
+	// one update, which matches nothing when the address does not exist.
+	set := bson.M{"addresses.$[target]": address, "updatedAt": time.Now()}
+	filters := []interface{}{bson.M{"target.id": addressID}}
+	if cleared := clearedDefaults(address); len(cleared) > 0 {
+		for field, value := range cleared {
+			set["addresses.$[other]."+field] = value
+		}
+		filters = append(filters, bson.M{"other.id": bson.M{"$ne": addressID}})
+	}
+	res, err := userCollection.UpdateOne(ctx,
+		notDeleted(bson.M{"_id": oid, "addresses.id": addressID}),
+		bson.M{"$set": set, "$inc": bson.M{"version": 1}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: filters}),
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to update address: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "Address not found")
+	}
+
+	return toAddressResponse(address), nil
+}
+
+func (s *UserServiceServer) DeleteAddress(ctx context.Context, req *pb.DeleteAddressRequest) (*pb.DeleteAddressResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	addressID, err := primitive.ObjectIDFromHex(req.AddressId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid address id: %v", err)
+	}
+
+	res, err := userCollection.UpdateOne(ctx,
+		notDeleted(bson.M{"_id": oid, "addresses.id": addressID}),
+		bson.M{
+			"$pull": bson.M{"addresses": bson.M{"id": addressID}},
+			"$set":  bson.M{"updatedAt": time.Now()},
+			"$inc":  bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete address: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "Address not found")
+	}
+
+	return &pb.DeleteAddressResponse{
+		Id:      req.AddressId,
+		Success: true,
+	}, nil
+}
+
+// clearedDefaults lists the default flags that address takes over from the
+// user's other addresses, with the value they get there.
+func clearedDefaults(address Address) bson.M {
+	cleared := bson.M{}
+	if address.DefaultShipping {
+		cleared["defaultShipping"] = false
+	}
+	if address.DefaultBilling {
+		cleared["defaultBilling"] = false
+	}
+	return cleared
+}
+
+func (s *UserServiceServer) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	if req.Name == "" || len(req.Name) > maxAPIKeyName {
+		return nil, status.Errorf(codes.InvalidArgument, "Name is required and must be at most %d characters", maxAPIKeyName)
+	}
+	if len(req.Scopes) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "At least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown scope: %s", scope)
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Expiry must not be negative")
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+	if !canHoldAPIKeys(user.Role) {
+		return nil, status.Errorf(codes.FailedPrecondition, "Only admin and integration users can hold API keys")
+	}
+
+	key, keyHash, err := newAPIKey()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to generate API key: %v", err)
+	}
+
+	now := time.Now()
+	apiKey := &APIKey{
+		ID:        primitive.NewObjectID(),
+		UserID:    oid,
+		Name:      req.Name,
+		KeyHash:   keyHash,
+		Prefix:    key[:len(apiKeyPrefix)+apiKeyDisplayLength],
+		Scopes:    req.Scopes,
+		CreatedAt: now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if _, err := apiKeyCollection.InsertOne(ctx, apiKey); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to store API key: %v", err)
+	}
+
+	return &pb.CreateAPIKeyResponse{
+		Key:    key,
+		ApiKey: toAPIKeyResponse(apiKey),
+	}, nil
+}
+
+func (s *UserServiceServer) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.APIKeysResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	cursor, err := apiKeyCollection.Find(ctx, bson.M{"user_id": oid}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch API keys: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*pb.APIKey
+	for cursor.Next(ctx) {
+		var apiKey APIKey
+		if err := cursor.Decode(&apiKey); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode API key: %v", err)
+		}
+		keys = append(keys, toAPIKeyResponse(&apiKey))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch API keys: %v", err)
+	}
+
+	return &pb.APIKeysResponse{ApiKeys: keys}, nil
+}
+
+func (s *UserServiceServer) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	keyID, err := primitive.ObjectIDFromHex(req.KeyId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid key id: %v", err)
+	}
+
+	res, err := apiKeyCollection.UpdateOne(ctx,
+		bson.M{"_id": keyID, "user_id": oid, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to revoke API key: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "No active API key with this id")
+	}
+
+	return &pb.RevokeAPIKeyResponse{
+		Id:      req.KeyId,
+		Success: true,
+	}, nil
+}
+
+// AuthenticateAPIKey lets the other services check keys presented to them.
+func (s *UserServiceServer) AuthenticateAPIKey(ctx context.Context, req *pb.AuthenticateAPIKeyRequest) (*pb.APIKeyPrincipal, error) {
+	apiKey, err := lookupAPIKey(ctx, req.Key, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid API key: %v", err)
+	}
+
+	return &pb.APIKeyPrincipal{
+		KeyId:  apiKey.ID.Hex(),
+		UserId: apiKey.UserID.Hex(),
+		Scopes: apiKey.Scopes,
+	}, nil
+}
+
+func (s *UserServiceServer) AssignRole(ctx context.Context, req *pb.AssignRoleRequest) (*pb.UserResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	if _, err := findRole(ctx, req.Role); err != nil {
+		return nil, err
+	}
+
+	return setUserRole(ctx, oid, bson.M{}, req.Role)
+}
+
+func (s *UserServiceServer) RevokeRole(ctx context.Context, req *pb.RevokeRoleRequest) (*pb.UserResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+	if req.Role == "" || req.Role == auth.RoleCustomer {
+		return nil, status.Errorf(codes.InvalidArgument, "The %s role cannot be revoked", auth.RoleCustomer)
+	}
+
+	claims, _ := auth.ClaimsFromContext(ctx)
+	if claims != nil && claims.Subject == req.UserId && req.Role == auth.RoleAdmin {
+		return nil, status.Errorf(codes.FailedPrecondition, "Admins cannot revoke their own admin role")
+	}
+
+	return setUserRole(ctx, oid, bson.M{"role": req.Role}, auth.RoleCustomer)
+}
+
+// setUserRole changes the role of a user matching filter. Access tokens
+// already issued keep the old role until they expire; the next refresh picks
+// up the new one.
+func setUserRole(ctx context.Context, oid primitive.ObjectID, filter bson.M, role string) (*pb.UserResponse, error) {
+	filter["_id"] = oid
+	res, err := userCollection.UpdateOne(ctx, notDeleted(filter), bson.M{
+		"$set": bson.M{"role": role, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to update role: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		if _, err := findUser(ctx, bson.M{"_id": oid}); err != nil {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "User does not have this role")
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+	return toUserResponse(user), nil
+}
+
+func (s *UserServiceServer) ListPermissions(ctx context.Context, req *pb.ListPermissionsRequest) (*pb.PermissionsResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid ObjectID: %v", err)
+	}
+
+	user, err := findUser(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.PermissionsResponse{Role: user.Role, Permissions: []string{}}
+	var role Role
+	err = roleCollection.FindOne(ctx, bson.M{"_id": user.Role}).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		// A role that is no longer defined grants nothing.
+		return resp, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to fetch role: %v", err)
+	}
+	resp.Permissions = role.Permissions
+	return resp, nil
+}